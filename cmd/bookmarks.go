@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/file"
+	"github.com/spf13/cobra"
+)
+
+var mergeDuplicates bool
+
+var bookmarksCmd = &cobra.Command{
+	Use:   "bookmarks files...",
+	Short: "Clean the URLs of browser bookmark exports",
+	Long: `Clean the URL of every bookmark in Netscape bookmark files (the bookmarks.html
+browsers export), Chrome Bookmarks files and Firefox JSON backups. Titles, dates,
+icons and every other field are left byte for byte as they were.
+
+Bookmarks that become duplicates of earlier ones once cleaned are listed on
+stderr. Use --merge-duplicates to remove them. The result is printed to stdout
+unless --write updates the files in place or --diff or --dry-run previews the
+changes.
+
+Examples:
+  littlewill bookmarks --diff bookmarks.html
+  littlewill bookmarks --write --merge-duplicates bookmarks-2024-05-01.json`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipeline, err := buildPipeline()
+		if err != nil {
+			return err
+		}
+		pipeline.MergeDuplicateBookmarks = mergeDuplicates
+
+		logger := LoggerFrom(cmd.Context())
+		failed := 0
+		for _, path := range args {
+			if err := cleanBookmarks(cmd, path, pipeline); err != nil {
+				logger.Error(err, "Failed to clean bookmarks", "path", path)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to clean %d of %d bookmark files", failed, len(args))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarksCmd)
+
+	bookmarksCmd.Flags().BoolVarP(&writeInPlace, "write", "w", false, "write result to the source files instead of stdout")
+	bookmarksCmd.Flags().BoolVar(&mergeDuplicates, "merge-duplicates", false, "remove bookmarks that become duplicates of earlier ones once cleaned")
+	addPreviewFlags(bookmarksCmd)
+}
+
+// cleanBookmarks cleans the bookmark export at path, lists its duplicates on
+// stderr and writes the result where the flags ask for it
+func cleanBookmarks(cmd *cobra.Command, path string, pipeline core.Pipeline) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	kind := file.DetectContent(path, content)
+	format, ok := core.BookmarkFormat(kind)
+	if !ok {
+		return fmt.Errorf("%s is not a bookmark export but %s", path, kind)
+	}
+
+	processedContent, _, duplicates, err := pipeline.ApplyBookmarks(format, content)
+	if err != nil {
+		return fmt.Errorf("failed to process input: %w", err)
+	}
+
+	for _, duplicate := range duplicates {
+		action := "duplicates"
+		if pipeline.MergeDuplicateBookmarks {
+			action = "removed as a duplicate of"
+		}
+		site := duplicate.Bookmark.Site
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d:%d: %q %s %q at line %d once cleaned to %s\n",
+			path, site.Line, site.Column, duplicate.Bookmark.Title, action, duplicate.Of.Title, duplicate.Of.Site.Line, duplicate.URL)
+	}
+
+	opts := previewOptions(cmd)
+	switch {
+	case opts.Preview():
+		return core.Preview(path, content, processedContent, opts)
+	case writeInPlace:
+		if bytes.Equal(content, processedContent) {
+			return nil
+		}
+		if err := os.WriteFile(path, processedContent, 0o644); err != nil {
+			return fmt.Errorf("failed to write processed content to file: %w", err)
+		}
+		return nil
+	default:
+		if _, err := cmd.OutOrStdout().Write(processedContent); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+}
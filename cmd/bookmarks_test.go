@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestCleanBookmarks(t *testing.T) {
+	const export = `{"guid":"root________","title":"","type":"text/x-moz-place-container","root":"placesRoot","children":[` +
+		`{"title":"One","type":"text/x-moz-place","uri":"https://example.com/?utm_source=x"},` +
+		`{"title":"Two","type":"text/x-moz-place","uri":"https://example.com/"}]}`
+
+	testCases := []struct {
+		name           string
+		merge          bool
+		expectedOut    string
+		expectedStderr string
+	}{
+		{
+			name:           "Duplicates are reported",
+			expectedOut:    strings.Replace(export, "?utm_source=x", "", 1),
+			expectedStderr: `"Two" duplicates "One" at line 1 once cleaned to https://example.com/`,
+		},
+		{
+			name:  "Duplicates are merged",
+			merge: true,
+			expectedOut: strings.NewReplacer(
+				"?utm_source=x", "",
+				`,{"title":"Two","type":"text/x-moz-place","uri":"https://example.com/"}`, "",
+			).Replace(export),
+			expectedStderr: `"Two" removed as a duplicate of "One" at line 1`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bookmarks-2024-05-01.json")
+			if err := os.WriteFile(path, []byte(export), 0o644); err != nil {
+				t.Fatalf("Failed to write input file: %v", err)
+			}
+
+			var out, stderr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&out)
+			cmd.SetErr(&stderr)
+
+			pipeline := core.NewPipeline([]links.Transform{links.GenericTracking})
+			pipeline.MergeDuplicateBookmarks = tc.merge
+			if err := cleanBookmarks(cmd, path, pipeline); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectedOut, out.String()); diff != "" {
+				t.Errorf("Unexpected stdout (-want +got):\n%s", diff)
+			}
+			if !strings.Contains(stderr.String(), tc.expectedStderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestCleanBookmarksRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(path, []byte("https://example.com/\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+
+	if err := cleanBookmarks(cmd, path, core.NewPipeline(nil)); err == nil {
+		t.Fatal("Expected an error for a file that is not a bookmark export, got nil")
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gkwa/littlewill/core/browser"
+	"github.com/spf13/cobra"
+)
+
+var browserDryRun bool
+
+var browserCmd = &cobra.Command{
+	Use:   "browser paths...",
+	Short: "Clean the URLs in the history and bookmarks of a browser profile",
+	Long: `Clean the URLs stored in a browser profile: Firefox's places.sqlite, which
+holds both history and bookmarks, and the History and Bookmarks files of Chrome
+and other Chromium browsers. Pass the files themselves or a profile directory
+to clean every store in it.
+
+The browser must be closed. Each store is copied to a .bak file next to it
+before it is changed, and databases are updated in a single transaction. Every
+cleaned URL is listed, along with URLs that were left alone because their
+cleaned form is already stored or lives on another site.
+
+Examples:
+  littlewill browser --dry-run ~/.mozilla/firefox/abcd1234.default-release
+  littlewill browser ~/.config/google-chrome/Default/History`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipeline, err := buildPipeline()
+		if err != nil {
+			return err
+		}
+		logger := LoggerFrom(cmd.Context())
+
+		failed := 0
+		for _, arg := range args {
+			stores, err := browser.FindStores(arg)
+			if err != nil {
+				logger.Error(err, "Failed to find browser stores", "path", arg)
+				failed++
+				continue
+			}
+			for _, path := range stores {
+				result, err := browser.Clean(cmd.Context(), path, pipeline, browser.Options{DryRun: browserDryRun})
+				if err != nil {
+					logger.Error(err, "Failed to clean browser store", "path", path)
+					failed++
+					continue
+				}
+				writeBrowserResult(cmd.OutOrStdout(), result, browserDryRun)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to clean %d browser store(s)", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(browserCmd)
+
+	browserCmd.Flags().BoolVar(&browserDryRun, "dry-run", false, "report the URLs that would change without writing anything")
+}
+
+// writeBrowserResult lists the changes to a store followed by a summary line
+func writeBrowserResult(w io.Writer, result browser.Result, dryRun bool) {
+	if result.Backup != "" {
+		fmt.Fprintf(w, "%s: backed up to %s\n", result.Path, result.Backup)
+	}
+	skipped := 0
+	for _, change := range result.Changes {
+		if change.Skipped != "" {
+			skipped++
+			fmt.Fprintf(w, "%s: %s: skipped %s: %s\n", result.Path, change.Location, change.Original, change.Skipped)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s: %s -> %s (%s)\n", result.Path, change.Location, change.Original, change.Cleaned, strings.Join(change.Transforms, ", "))
+	}
+
+	verb := "updated"
+	if dryRun {
+		verb = "would be updated"
+	}
+	summary := fmt.Sprintf("%d URL(s) %s, %d skipped", result.Updated(), verb, skipped)
+	if result.Store == browser.FirefoxPlaces {
+		summary += fmt.Sprintf(", %d bookmark(s) affected", result.Bookmarks)
+	}
+	fmt.Fprintf(w, "%s: %s\n", result.Path, summary)
+}
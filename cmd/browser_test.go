@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gkwa/littlewill/core/browser"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteBrowserResult(t *testing.T) {
+	result := browser.Result{
+		Path:   "places.sqlite",
+		Store:  browser.FirefoxPlaces,
+		Backup: "places.sqlite.bak",
+		Changes: []browser.Change{
+			{Location: "moz_places 1", Original: "https://example.com/?utm_source=x", Cleaned: "https://example.com/", Transforms: []string{"generic-tracking"}},
+			{Location: "moz_places 2", Original: "https://example.com/?fbclid=y", Cleaned: "https://example.com/", Transforms: []string{"generic-tracking"}, Skipped: "the cleaned URL is already moz_places 1"},
+		},
+		Bookmarks: 3,
+	}
+
+	expected := `places.sqlite: backed up to places.sqlite.bak
+places.sqlite: moz_places 1: https://example.com/?utm_source=x -> https://example.com/ (generic-tracking)
+places.sqlite: moz_places 2: skipped https://example.com/?fbclid=y: the cleaned URL is already moz_places 1
+places.sqlite: 1 URL(s) updated, 1 skipped, 3 bookmark(s) affected
+`
+
+	var out bytes.Buffer
+	writeBrowserResult(&out, result, false)
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("Unexpected output (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// exitChangesNeeded is returned by check when at least one file would be changed
+	exitChangesNeeded = 1
+	// exitIOError is returned by check when at least one file could not be read or processed
+	exitIOError = 2
+	// exitConfigError is returned by check when its flags or the config are invalid
+	exitConfigError = 3
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [files...]",
+	Short: "Report files whose links need cleaning",
+	Long: `Run the enabled transforms over the given files without writing anything and
+list every file whose content would change.
+
+With no files, paths are read from stdin one per line. The exit status is 0 when
+all files are clean, 1 when any file needs cleaning, 2 when any file could not
+be read or processed and 3 when the flags or the config are invalid, which makes
+check suitable for CI and pre-commit hooks.
+
+Examples:
+  littlewill check README.md docs/*.md
+  git ls-files '*.md' | littlewill check
+  littlewill check --diff notes/*.md`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipeline, err := buildPipeline()
+		if err != nil {
+			return &exitError{code: exitConfigError, err: err}
+		}
+
+		opts := previewOptions(cmd)
+		opts.DryRun = true
+
+		var summary core.Summary
+		if len(args) > 0 {
+			summary = core.ProcessPaths(cmd.Context(), args, opts, pipeline)
+		} else {
+			var err error
+			summary, err = core.ProcessPathsFromStdin(cmd.Context(), opts, pipeline)
+			if err != nil {
+				return &exitError{code: exitIOError, err: fmt.Errorf("failed to read paths from stdin: %w", err)}
+			}
+		}
+
+		return checkResult(summary)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &exitError{code: exitConfigError, err: err}
+	})
+
+	checkCmd.Flags().BoolVar(&showDiff, "diff", false, "print a unified diff for each file that needs cleaning")
+	addReportFlag(checkCmd)
+}
+
+// checkResult maps a summary to the exit status check reports
+func checkResult(summary core.Summary) error {
+	switch {
+	case len(summary.Failed) > 0:
+		return &exitError{code: exitIOError, err: fmt.Errorf("%d file(s) could not be checked", len(summary.Failed))}
+	case len(summary.Changed) > 0:
+		return &exitError{code: exitChangesNeeded, err: fmt.Errorf("%d file(s) need cleaning", len(summary.Changed))}
+	default:
+		return nil
+	}
+}
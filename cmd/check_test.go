@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	cleanPath := filepath.Join(dir, "clean.md")
+	dirtyPath := filepath.Join(dir, "dirty.md")
+	missingPath := filepath.Join(dir, "missing.md")
+
+	const dirty = "Search: https://www.google.com/search?q=test&hl=en\n"
+	files := map[string]string{
+		cleanPath: "Search: https://www.google.com/search?q=test\n",
+		dirtyPath: dirty,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			name:         "Clean files exit zero",
+			args:         []string{cleanPath},
+			expectedCode: 0,
+		},
+		{
+			name:         "Files needing changes are listed",
+			args:         []string{cleanPath, dirtyPath},
+			expectedCode: exitChangesNeeded,
+			expectedOut:  "would update " + dirtyPath,
+		},
+		{
+			name:         "Unreadable files take precedence over changes",
+			args:         []string{dirtyPath, missingPath},
+			expectedCode: exitIOError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&out)
+
+			err := checkCmd.RunE(cmd, tc.args)
+
+			code := 0
+			if err != nil {
+				var exitErr *exitError
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("Expected an exitError, got %v", err)
+				}
+				code = exitErr.code
+			}
+			if code != tc.expectedCode {
+				t.Errorf("Exit code = %d, want %d", code, tc.expectedCode)
+			}
+			if !strings.Contains(out.String(), tc.expectedOut) {
+				t.Errorf("Expected output to contain %q, got %q", tc.expectedOut, out.String())
+			}
+
+			content, err := os.ReadFile(dirtyPath)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(content) != dirty {
+				t.Errorf("check must not modify files, got %q", string(content))
+			}
+		})
+	}
+}
+
+func TestCheckCommandInvalidInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clean.md")
+	if err := os.WriteFile(path, []byte("Nothing to clean\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+
+	testCases := []struct {
+		name           string
+		args           []string
+		sites          any
+		expectedStderr string
+	}{
+		{
+			name:           "Unknown flag",
+			args:           []string{"check", "--bogus", path},
+			expectedStderr: "unknown flag: --bogus",
+		},
+		{
+			name:           "Invalid site rule",
+			args:           []string{"check", path},
+			sites:          []any{map[string]any{"name": "Bad Name", "domains": []any{"example.com"}}},
+			expectedStderr: `site rule "Bad Name"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("sites", tc.sites)
+			t.Cleanup(func() { viper.Set("sites", nil) })
+
+			rootCmd.SetArgs(tc.args)
+			t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+			var stderr bytes.Buffer
+			code := exitCode(rootCmd.Execute(), &stderr)
+			if code != exitConfigError {
+				t.Errorf("Exit code = %d, want %d", code, exitConfigError)
+			}
+			if !strings.Contains(stderr.String(), tc.expectedStderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestCheckCommandJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.md", "b.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("Search: https://www.google.com/search?q=test&hl=en\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+
+	for _, diff := range []bool{false, true} {
+		t.Run(fmt.Sprintf("diff=%v", diff), func(t *testing.T) {
+			reportJSON, showDiff = true, diff
+			t.Cleanup(func() { reportJSON, showDiff = false, false })
+
+			var stdout, stderr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			var exitErr *exitError
+			if err := checkCmd.RunE(cmd, paths); !errors.As(err, &exitErr) || exitErr.code != exitChangesNeeded {
+				t.Fatalf("Expected exit code %d, got %v", exitChangesNeeded, err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			if len(lines) != len(paths) {
+				t.Fatalf("Expected %d JSON lines, got %q", len(paths), stdout.String())
+			}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Errorf("Stdout line %q is not JSON: %v", line, err)
+				}
+			}
+			if !strings.Contains(stderr.String(), paths[0]) {
+				t.Errorf("Expected the preview on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
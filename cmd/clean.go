@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/spf13/cobra"
+)
+
+var writeInPlace bool
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean [files...]",
+	Short: "Clean links in files and print the result",
+	Long: `Clean links in the given files and write the result to stdout.
+
+Use - as a file name to read content from stdin. With no files, stdin is read,
+so clean can be used as a filter from editors and scripts. Use --write to
+update the files in place instead of printing them, or --diff to see what
+would change.
+
+Examples:
+  littlewill clean input.md > output.md
+  littlewill clean --write notes/*.md
+  littlewill clean --diff notes/*.md
+  pbpaste | littlewill clean | pbcopy`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		pipeline, err := buildPipeline()
+		if err != nil {
+			return err
+		}
+		return cleanPaths(cmd, args, pipeline)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	cleanCmd.Flags().BoolVarP(&writeInPlace, "write", "w", false, "write result to the source files instead of stdout")
+	addPreviewFlags(cleanCmd)
+}
+
+func cleanPaths(cmd *cobra.Command, paths []string, pipeline core.Pipeline) error {
+	logger := LoggerFrom(cmd.Context())
+	opts := previewOptions(cmd)
+
+	failed := 0
+	for _, path := range paths {
+		var err error
+		switch {
+		case path == "-" && opts.Preview():
+			err = previewStream(cmd.InOrStdin(), opts, pipeline)
+		case path == "-":
+			err = core.ProcessStream(cmd.InOrStdin(), cmd.OutOrStdout(), pipeline)
+		case writeInPlace || opts.Preview():
+			_, err = core.ProcessFileWithOptions(logger, path, opts, pipeline)
+		default:
+			err = cleanFileToWriter(path, cmd.OutOrStdout(), pipeline)
+		}
+		if err != nil {
+			logger.Error(err, "Failed to clean", "path", path)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to clean %d of %d inputs", failed, len(paths))
+	}
+	return nil
+}
+
+func cleanFileToWriter(path string, w io.Writer, pipeline core.Pipeline) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	processedContent, _, err := pipeline.ApplyFile(path, content)
+	if err != nil {
+		return fmt.Errorf("failed to process input: %w", err)
+	}
+
+	_, err = w.Write(processedContent)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func previewStream(r io.Reader, opts core.Options, pipeline core.Pipeline) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	processedContent, _, err := pipeline.Apply(content)
+	if err != nil {
+		return fmt.Errorf("failed to process input: %w", err)
+	}
+
+	return core.Preview("-", content, processedContent, opts)
+}
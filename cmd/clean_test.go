@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestCleanPaths(t *testing.T) {
+	const (
+		dirty = "Search: https://www.google.com/search?q=test&hl=en\n"
+		clean = "Search: https://www.google.com/search?q=test\n"
+	)
+
+	testCases := []struct {
+		name         string
+		write        bool
+		stdin        string
+		useFile      bool
+		useStdin     bool
+		expectedOut  string
+		expectedFile string
+	}{
+		{
+			name:         "File is printed to stdout and left untouched",
+			useFile:      true,
+			expectedOut:  clean,
+			expectedFile: dirty,
+		},
+		{
+			name:         "File is rewritten in place with write",
+			write:        true,
+			useFile:      true,
+			expectedOut:  "",
+			expectedFile: clean,
+		},
+		{
+			name:        "Dash reads content from stdin",
+			stdin:       dirty,
+			useStdin:    true,
+			expectedOut: clean,
+		},
+		{
+			name:        "Dash reads content from stdin even with write",
+			write:       true,
+			stdin:       dirty,
+			useStdin:    true,
+			expectedOut: clean,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oldWrite := writeInPlace
+			writeInPlace = tc.write
+			defer func() { writeInPlace = oldWrite }()
+
+			path := filepath.Join(t.TempDir(), "input.md")
+			if err := os.WriteFile(path, []byte(dirty), 0o644); err != nil {
+				t.Fatalf("Failed to write input file: %v", err)
+			}
+
+			var args []string
+			if tc.useFile {
+				args = append(args, path)
+			}
+			if tc.useStdin {
+				args = append(args, "-")
+			}
+
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetIn(strings.NewReader(tc.stdin))
+			cmd.SetOut(&out)
+
+			err := cleanPaths(cmd, args, core.NewPipeline([]links.Transform{links.Google}))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectedOut, out.String()); diff != "" {
+				t.Errorf("Unexpected stdout (-want +got):\n%s", diff)
+			}
+
+			if tc.useFile {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read file: %v", err)
+				}
+				if diff := cmp.Diff(tc.expectedFile, string(content)); diff != "" {
+					t.Errorf("Unexpected file content (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanPathsMissingFile(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+
+	err := cleanPaths(cmd, []string{filepath.Join(t.TempDir(), "missing.md")}, core.Pipeline{})
+	if err == nil {
+		t.Fatal("Expected an error for a missing file, got nil")
+	}
+}
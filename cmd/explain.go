@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <url>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Show how each transform changes a URL",
+	Long: `Run a single URL through the enabled URL transforms, repeating them until
+the URL no longer changes as cleaning a file does, and print the URL after each
+rewrite, along with the parameters removed and the host, path or fragment
+rewritten by the transform responsible.
+
+Examples:
+  littlewill explain 'https://www.google.com/search?q=go&hl=en&ei=abc'
+  littlewill explain --enable-reddit=false 'https://www.reddit.com/r/golang'`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pipeline, err := buildPipeline()
+		if err != nil {
+			return err
+		}
+		return explainURL(cmd.OutOrStdout(), args[0], pipeline)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
+
+func explainURL(w io.Writer, rawURL string, pipeline core.Pipeline) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("not an absolute URL: %q", rawURL)
+	}
+
+	steps, result, err := pipeline.TraceURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, step := range steps {
+		width = max(width, len(step.Transform))
+	}
+
+	fmt.Fprintf(w, "input:  %s\n", rawURL)
+	for i, step := range steps {
+		fmt.Fprintf(w, "%3d. %-*s  %s\n", i+1, width, step.Transform, step.After)
+		for _, change := range core.DescribeURLChange(step.Before, step.After) {
+			fmt.Fprintf(w, "     %-*s    %s\n", width, "", change)
+		}
+	}
+	fmt.Fprintf(w, "result: %s\n", result)
+
+	return nil
+}
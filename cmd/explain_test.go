@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+)
+
+func TestExplainURL(t *testing.T) {
+	pipeline := core.NewPipeline([]links.Transform{links.GenericTracking, links.Reddit})
+
+	var out bytes.Buffer
+	err := explainURL(&out, "https://www.reddit.com/r/golang?utm_source=share", pipeline)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `input:  https://www.reddit.com/r/golang?utm_source=share
+  1. generic-tracking  https://www.reddit.com/r/golang
+                         removed params: utm_source
+  2. reddit            https://www.reddit.com/r/golang/
+                         path: /r/golang -> /r/golang/
+result: https://www.reddit.com/r/golang/
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("Unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestExplainURLMatchesClean(t *testing.T) {
+	viper.Set("clearurls", "../core/links/testdata/clearurls.min.json")
+	t.Cleanup(func() { viper.Set("clearurls", "") })
+
+	pipeline, err := buildPipeline()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	const input = "https://www.google.com/url?q=https://example.com/%3Futm_source%3Dx"
+	cleaned, _, err := pipeline.ApplyURLs([]byte(input), links.TextURLs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(cleaned) != "https://example.com/" {
+		t.Fatalf("ApplyURLs() = %q, want %q", cleaned, "https://example.com/")
+	}
+
+	var out bytes.Buffer
+	if err := explainURL(&out, input, pipeline); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `input:  https://www.google.com/url?q=https://example.com/%3Futm_source%3Dx
+  1. google            https://www.google.com/url?q=https%3A%2F%2Fexample.com%2F%3Futm_source%3Dx
+                         query: q=https://example.com/%3Futm_source%3Dx -> q=https%3A%2F%2Fexample.com%2F%3Futm_source%3Dx
+  2. clearurls-google  https://example.com/?utm_source=x
+                         host: www.google.com -> example.com
+                         path: /url -> /
+                         removed params: q
+                         added params: utm_source
+  3. generic-tracking  https://example.com/
+                         removed params: utm_source
+result: https://example.com/
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("Unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestExplainURLRejectsRelativeURLs(t *testing.T) {
+	var out bytes.Buffer
+	if err := explainURL(&out, "example.com/path", core.Pipeline{}); err == nil {
+		t.Error("Expected an error for a URL without scheme, got nil")
+	}
+}
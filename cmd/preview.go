@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dryRun     bool
+	showDiff   bool
+	reportJSON bool
+)
+
+// addPreviewFlags adds the --dry-run and --diff flags shared by every command that writes files
+func addPreviewFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report files that would change without writing them")
+	cmd.Flags().BoolVar(&showDiff, "diff", false, "print a unified diff of changes without writing them")
+}
+
+// addReportFlag adds the --json flag to commands that can report per-URL changes
+func addReportFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&reportJSON, "json", false, "print a JSON line per changed file listing each URL change")
+}
+
+// previewOptions builds core.Options from the preview flags, writing to the
+// command's output. With --json the output carries the JSON records alone, and
+// dry-run and diff output go to the command's error output instead.
+func previewOptions(cmd *cobra.Command) core.Options {
+	opts := core.Options{
+		DryRun: dryRun,
+		Diff:   showDiff,
+		Out:    cmd.OutOrStdout(),
+	}
+	if reportJSON {
+		opts.Report = opts.Out
+		opts.Out = cmd.ErrOrStderr()
+	}
+	opts.Color = useColor(opts.Out)
+	return opts
+}
+
+// useColor reports whether w is a terminal that should receive colored output
+func useColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
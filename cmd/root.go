@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/gkwa/littlewill/internal/logger"
@@ -34,11 +36,39 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// exitError carries a specific process exit status out of a command
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if code := exitCode(rootCmd.Execute(), os.Stderr); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// exitCode returns the exit status for the error a command returned, writing
+// the errors that carry their own status to stderr. Cobra has already
+// printed the others.
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		fmt.Fprintln(stderr, exitErr)
+		return exitErr.code
 	}
+	return 1
 }
 
 func init() {
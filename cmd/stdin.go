@@ -11,11 +11,14 @@ var pathsFromStdinCmd = &cobra.Command{
 	Short:   "Process a list of paths from stdin",
 	Long:    `This command reads a list of file paths from standard input and processes them, cleaning up markdown links in each file.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		transforms := buildLinkTransforms()
-		core.ProcessPathsFromStdin(cmd.Context(), transforms...)
+		pipeline, err := buildPipeline()
+		cobra.CheckErr(err)
+		core.ProcessPathsFromStdin(cmd.Context(), previewOptions(cmd), pipeline)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(pathsFromStdinCmd)
+	addPreviewFlags(pathsFromStdinCmd)
+	addReportFlag(pathsFromStdinCmd)
 }
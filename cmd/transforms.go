@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/gkwa/littlewill/core"
 	"github.com/gkwa/littlewill/core/links"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
 )
 
 // TransformDefinition defines a URL transformation with its metadata
@@ -15,164 +23,43 @@ type TransformDefinition struct {
 	FlagName       string
 	Description    string
 	Function       func(io.Reader, io.Writer) error
+	OrgFunction    func(io.Reader, io.Writer) error // the counterpart of Function for Org documents, if any
+	Transform      links.Transform                  // set for URL transforms, nil for document transforms
+	SyncLinkText   bool                             // set for link text syncing, which runs within the URL pass
 	DefaultEnabled bool
 }
 
-// AllTransforms is the single source of truth for all available transformations
-var AllTransforms = []TransformDefinition{
-	{
-		Name:           "generic-tracking",
-		ConfigKey:      "transforms.generic_tracking",
-		FlagName:       "enable-generic-tracking",
-		Description:    "Enable generic tracking parameter removal",
-		Function:       links.RemoveGenericTrackingParams,
+// AllTransforms is the single source of truth for all available transformations.
+// URL transforms and their metadata come from links.Builtin; transforms that
+// work on whole documents are listed here.
+var AllTransforms = append(
+	urlTransformDefinitions(links.Builtin()),
+	TransformDefinition{
+		Name:           "markdown-link-text",
+		ConfigKey:      "transforms.markdown_link_text",
+		FlagName:       "enable-markdown-link-text",
+		Description:    "Enable syncing markdown link text that shows the URL with the cleaned URL",
+		SyncLinkText:   true,
 		DefaultEnabled: true,
 	},
-	{
-		Name:           "google",
-		ConfigKey:      "transforms.google",
-		FlagName:       "enable-google",
-		Description:    "Enable Google URL parameter removal",
-		Function:       links.RemoveParamsFromGoogleURLs,
+	TransformDefinition{
+		Name:           "markdown-link-whitespace",
+		ConfigKey:      "transforms.markdown_link_whitespace",
+		FlagName:       "enable-markdown-link-whitespace",
+		Description:    "Enable whitespace cleanup in markdown link text and org link descriptions",
+		Function:       links.RemoveWhitespaceFromMarkdownLinks,
+		OrgFunction:    links.RemoveWhitespaceFromOrgLinks,
 		DefaultEnabled: true,
 	},
-	{
-		Name:           "youtube",
-		ConfigKey:      "transforms.youtube",
-		FlagName:       "enable-youtube",
-		Description:    "Enable YouTube URL parameter removal",
-		Function:       links.RemoveParamsFromYouTubeURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "substack",
-		ConfigKey:      "transforms.substack",
-		FlagName:       "enable-substack",
-		Description:    "Enable Substack URL parameter removal",
-		Function:       links.RemoveParamsFromSubstackURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "thesweekly",
-		ConfigKey:      "transforms.thesweekly",
-		FlagName:       "enable-thesweekly",
-		Description:    "Enable TheSweekly URL parameter removal",
-		Function:       links.RemoveParamsFromTheSweeklyURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "techcrunch",
-		ConfigKey:      "transforms.techcrunch",
-		FlagName:       "enable-techcrunch",
-		Description:    "Enable TechCrunch URL parameter removal",
-		Function:       links.RemoveParamsFromTechCrunchURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "facebook",
-		ConfigKey:      "transforms.facebook",
-		FlagName:       "enable-facebook",
-		Description:    "Enable Facebook URL parameter removal",
-		Function:       links.RemoveParamsFromFacebookURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "linkedin",
-		ConfigKey:      "transforms.linkedin",
-		FlagName:       "enable-linkedin",
-		Description:    "Enable LinkedIn URL parameter removal",
-		Function:       links.RemoveParamsFromLinkedInURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "wsj",
-		ConfigKey:      "transforms.wsj",
-		FlagName:       "enable-wsj",
-		Description:    "Enable WSJ URL parameter removal",
-		Function:       links.RemoveParamsFromWSJURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "reddit",
-		ConfigKey:      "transforms.reddit",
-		FlagName:       "enable-reddit",
-		Description:    "Enable Reddit URL parameter removal",
-		Function:       links.RemoveParamsFromRedditURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "shopify",
-		ConfigKey:      "transforms.shopify",
-		FlagName:       "enable-shopify",
-		Description:    "Enable Shopify URL parameter removal",
-		Function:       links.RemoveParamsFromShopifyURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "amazon",
-		ConfigKey:      "transforms.amazon",
-		FlagName:       "enable-amazon",
-		Description:    "Enable Amazon URL parameter removal",
-		Function:       links.RemoveParamsFromAmazonURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "bloomberg",
-		ConfigKey:      "transforms.bloomberg",
-		FlagName:       "enable-bloomberg",
-		Description:    "Enable Bloomberg URL parameter removal",
-		Function:       links.RemoveParamsFromBloombergURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "netflix",
-		ConfigKey:      "transforms.netflix",
-		FlagName:       "enable-netflix",
-		Description:    "Enable Netflix URL parameter removal",
-		Function:       links.RemoveParamsFromNetflixURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "instagram",
-		ConfigKey:      "transforms.instagram",
-		FlagName:       "enable-instagram",
-		Description:    "Enable Instagram URL parameter removal",
-		Function:       links.RemoveParamsFromInstagramURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "tiktok",
-		ConfigKey:      "transforms.tiktok",
-		FlagName:       "enable-tiktok",
-		Description:    "Enable TikTok URL parameter removal",
-		Function:       links.RemoveParamsFromTikTokURLs,
-		DefaultEnabled: true,
+	TransformDefinition{
+		Name:           "markdown-link-titles",
+		ConfigKey:      "transforms.markdown_link_titles",
+		FlagName:       "enable-markdown-link-titles",
+		Description:    "Enable title removal from markdown links",
+		Function:       links.RemoveTitlesFromMarkdownLinks,
+		DefaultEnabled: false,
 	},
-	{
-		Name:           "walmart",
-		ConfigKey:      "transforms.walmart",
-		FlagName:       "enable-walmart",
-		Description:    "Enable Walmart URL parameter removal",
-		Function:       links.RemoveParamsFromWalmartURLs,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "conditional",
-		ConfigKey:      "transforms.conditional",
-		FlagName:       "enable-conditional",
-		Description:    "Enable conditional parameter removal",
-		Function:       links.RemoveConditionalParams,
-		DefaultEnabled: true,
-	},
-	{
-		Name:           "text-fragments",
-		ConfigKey:      "transforms.text_fragments",
-		FlagName:       "enable-text-fragments",
-		Description:    "Enable text fragment removal",
-		Function:       links.RemoveTextFragmentsFromURLs,
-		DefaultEnabled: true,
-	},
-	{
+	TransformDefinition{
 		Name:           "youtube-count",
 		ConfigKey:      "transforms.youtube_count",
 		FlagName:       "enable-youtube-count",
@@ -180,19 +67,191 @@ var AllTransforms = []TransformDefinition{
 		Function:       links.RemoveYouTubeCountFromMarkdownLinks,
 		DefaultEnabled: true,
 	},
+)
+
+// urlTransformDefinitions derives config keys, flags and descriptions from each transform's name
+func urlTransformDefinitions(transforms []links.Transform) []TransformDefinition {
+	definitions := make([]TransformDefinition, 0, len(transforms))
+	for _, transform := range transforms {
+		definitions = append(definitions, TransformDefinition{
+			Name:           transform.Name(),
+			ConfigKey:      "transforms." + strings.ReplaceAll(transform.Name(), "-", "_"),
+			FlagName:       "enable-" + transform.Name(),
+			Description:    "Enable " + transform.Description(),
+			Function:       links.AsFunc(transform),
+			Transform:      transform,
+			DefaultEnabled: true,
+		})
+	}
+	return definitions
 }
 
-// buildLinkTransforms creates the list of enabled transformations based on configuration
-func buildLinkTransforms() []func(io.Reader, io.Writer) error {
-	var transforms []func(io.Reader, io.Writer) error
+// configSiteTransforms returns the transforms of the site rules listed under
+// sites in the config
+func configSiteTransforms() ([]links.Transform, error) {
+	sites := viper.Get("sites")
+	if sites == nil {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(map[string]any{"sites": sites})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read site rules from config: %w", err)
+	}
+	rules, err := links.ParseSiteRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
 
-	for _, transform := range AllTransforms {
-		if viper.GetBool(transform.ConfigKey) {
-			transforms = append(transforms, transform.Function)
+	var transforms []links.Transform
+	for _, rule := range rules {
+		transform, err := links.NewSiteTransform(rule)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms, nil
+}
+
+// configClearURLsTransforms returns the transforms of the ClearURLs rules
+// database at the path clearurls names in the config
+func configClearURLsTransforms() ([]links.Transform, error) {
+	path := viper.GetString("clearurls")
+	if path == "" {
+		return nil, nil
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	transforms, skipped, err := links.LoadClearURLs(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	logger := LoggerFrom(context.Background())
+	for _, pattern := range skipped {
+		logger.V(1).Info("Skipped ClearURLs pattern", "pattern", pattern)
+	}
+	return transforms, nil
+}
+
+// configFilterListTransforms returns the transforms of the filter lists
+// whose paths are listed under filter_lists in the config
+func configFilterListTransforms() ([]links.Transform, error) {
+	logger := LoggerFrom(context.Background())
+	var transforms []links.Transform
+	for _, path := range viper.GetStringSlice("filter_lists") {
+		path, err := expandHome(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		transform, skipped, err := links.LoadFilterList(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		if slices.ContainsFunc(transforms, func(t links.Transform) bool { return t.Name() == transform.Name() }) {
+			return nil, fmt.Errorf("config: filter lists named %q twice, rename one of the files", transform.Name())
+		}
+		for _, filter := range skipped {
+			logger.V(1).Info("Skipped filter", "filter", filter)
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms, nil
+}
+
+// expandHome expands a leading ~/ in a path from the config to the home
+// directory
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// transformDefinitions returns AllTransforms along with the site rules,
+// ClearURLs providers and filter lists from the config. A rule named after a
+// built-in URL transform replaces it; the others run after the built-in URL
+// transforms and are enabled unless their config key says otherwise.
+func transformDefinitions() ([]TransformDefinition, error) {
+	var configured []links.Transform
+	for _, load := range []func() ([]links.Transform, error){
+		configSiteTransforms,
+		configClearURLsTransforms,
+		configFilterListTransforms,
+	} {
+		transforms, err := load()
+		if err != nil {
+			return nil, err
+		}
+		configured = append(configured, transforms...)
+	}
+
+	definitions := slices.Clone(AllTransforms)
+	next := 0
+	for i, definition := range definitions {
+		if definition.Transform != nil {
+			next = i + 1
+		}
+	}
+	for _, definition := range urlTransformDefinitions(configured) {
+		i := slices.IndexFunc(definitions, func(d TransformDefinition) bool { return d.Name == definition.Name })
+		switch {
+		case i < 0:
+			viper.SetDefault(definition.ConfigKey, true)
+			definitions = slices.Insert(definitions, next, definition)
+			next++
+		case definitions[i].Transform != nil:
+			definitions[i] = definition
+		default:
+			return nil, fmt.Errorf("config: %q has the name of a document transform", definition.Name)
 		}
 	}
+	return definitions, nil
+}
 
-	return transforms
+// buildPipeline creates a pipeline of the enabled transformations based on configuration.
+// URL transforms run in a single pass; document transforms run afterwards in order.
+func buildPipeline() (core.Pipeline, error) {
+	definitions, err := transformDefinitions()
+	if err != nil {
+		return core.Pipeline{}, err
+	}
+
+	var urlTransforms []links.Transform
+	var documentTransforms, orgTransforms []core.NamedTransform
+	syncLinkText := false
+	for _, transform := range definitions {
+		if !viper.GetBool(transform.ConfigKey) {
+			continue
+		}
+		switch {
+		case transform.SyncLinkText:
+			syncLinkText = true
+		case transform.Transform != nil:
+			urlTransforms = append(urlTransforms, transform.Transform)
+		default:
+			documentTransforms = append(documentTransforms, core.NamedTransform{
+				Name: transform.Name,
+				Func: transform.Function,
+			})
+			if transform.OrgFunction != nil {
+				orgTransforms = append(orgTransforms, core.NamedTransform{
+					Name: transform.Name,
+					Func: transform.OrgFunction,
+				})
+			}
+		}
+	}
+	pipeline := core.NewPipeline(urlTransforms, documentTransforms...)
+	pipeline.Org = orgTransforms
+	pipeline.Engine.SyncLinkText = syncLinkText
+	return pipeline, nil
 }
 
 // setupTransformFlags adds flags and config bindings for all transforms
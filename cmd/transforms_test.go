@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestAllTransformsConfigKeys(t *testing.T) {
+	// Config keys and flag names are user facing and must stay stable
+	expected := map[string][2]string{
+		"generic-tracking":         {"transforms.generic_tracking", "enable-generic-tracking"},
+		"google":                   {"transforms.google", "enable-google"},
+		"text-fragments":           {"transforms.text_fragments", "enable-text-fragments"},
+		"youtube-count":            {"transforms.youtube_count", "enable-youtube-count"},
+		"wsj":                      {"transforms.wsj", "enable-wsj"},
+		"markdown-link-whitespace": {"transforms.markdown_link_whitespace", "enable-markdown-link-whitespace"},
+		"markdown-link-titles":     {"transforms.markdown_link_titles", "enable-markdown-link-titles"},
+		"markdown-link-text":       {"transforms.markdown_link_text", "enable-markdown-link-text"},
+	}
+
+	found := map[string]bool{}
+	for _, transform := range AllTransforms {
+		if transform.Function == nil && !transform.SyncLinkText {
+			t.Errorf("Transform %q has no function", transform.Name)
+		}
+		want, ok := expected[transform.Name]
+		if !ok {
+			continue
+		}
+		found[transform.Name] = true
+		if transform.ConfigKey != want[0] || transform.FlagName != want[1] {
+			t.Errorf("Transform %q = (%q, %q), want (%q, %q)",
+				transform.Name, transform.ConfigKey, transform.FlagName, want[0], want[1])
+		}
+	}
+
+	for name := range expected {
+		if !found[name] {
+			t.Errorf("Transform %q is missing from AllTransforms", name)
+		}
+	}
+}
+
+func TestBuildPipelineConfigSites(t *testing.T) {
+	var config map[string]any
+	err := yaml.Unmarshal([]byte(`
+sites:
+  - name: example
+    domains: [example.com]
+    params: [ref_id]
+  - name: walmart
+    domains: [walmart.com]
+    params: [athbdg]
+`), &config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	viper.Set("sites", config["sites"])
+	t.Cleanup(func() { viper.Set("sites", nil) })
+
+	pipeline, err := buildPipeline()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := "https://example.com/a?ref_id=1&id=2\nhttps://www.walmart.com/ip/1?athbdg=L1&classType=REGULAR\n"
+	// the walmart rule replaces the built-in one, which removed classType too
+	expected := "https://example.com/a?id=2\nhttps://www.walmart.com/ip/1?classType=REGULAR\n"
+	result, _, err := pipeline.ApplyURLs([]byte(input), links.TextURLs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(result) != expected {
+		t.Errorf("ApplyURLs() = %q, want %q", result, expected)
+	}
+}
+
+func TestBuildPipelineInvalidConfigSites(t *testing.T) {
+	viper.Set("sites", []any{map[string]any{"name": "markdown-link-titles", "domains": []any{"example.com"}}})
+	t.Cleanup(func() { viper.Set("sites", nil) })
+
+	if _, err := buildPipeline(); err == nil {
+		t.Error("Expected an error for a site rule named after a document transform")
+	}
+}
+
+func TestBuildPipelineConfigClearURLs(t *testing.T) {
+	viper.Set("clearurls", "../core/links/testdata/clearurls.min.json")
+	viper.Set("transforms.clearurls_bing", false)
+	t.Cleanup(func() {
+		viper.Set("clearurls", "")
+		viper.Set("transforms.clearurls_bing", nil)
+	})
+
+	pipeline, err := buildPipeline()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := "https://www.google.com/search?q=kettle&ei=abc\nhttps://www.bing.com/search?q=kettle&form=QBLH\n"
+	expected := "https://www.google.com/search?q=kettle\nhttps://www.bing.com/search?q=kettle&form=QBLH\n"
+	result, _, err := pipeline.ApplyURLs([]byte(input), links.TextURLs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(result) != expected {
+		t.Errorf("ApplyURLs() = %q, want %q", result, expected)
+	}
+}
+
+func TestBuildPipelineMissingClearURLs(t *testing.T) {
+	viper.Set("clearurls", "testdata/does-not-exist.json")
+	t.Cleanup(func() { viper.Set("clearurls", "") })
+
+	if _, err := buildPipeline(); err == nil {
+		t.Error("Expected an error for a missing ClearURLs rules file")
+	}
+}
+
+func TestBuildPipelineConfigFilterLists(t *testing.T) {
+	viper.Set("filter_lists", []string{"../core/links/testdata/removeparam.txt"})
+	t.Cleanup(func() { viper.Set("filter_lists", nil) })
+
+	pipeline, err := buildPipeline()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input := "https://www.example.com/a?session=abc&id=1\n"
+	expected := "https://www.example.com/a?id=1\n"
+	result, _, err := pipeline.ApplyURLs([]byte(input), links.TextURLs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(result) != expected {
+		t.Errorf("ApplyURLs() = %q, want %q", result, expected)
+	}
+}
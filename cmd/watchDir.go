@@ -23,22 +23,27 @@ all files will be watched. Patterns use standard glob syntax.
 Examples:
   littlewill watch-dir /path/to/directory
   littlewill watch-dir /path/to/directory --patterns "*.md,*.txt"
-  littlewill watch-dir /path/to/directory --patterns "doc_*.md" --patterns "report_*.txt"`,
+  littlewill watch-dir /path/to/directory --patterns "doc_*.md" --patterns "report_*.txt"
+  littlewill watch-dir /path/to/directory --diff`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dir := args[0]
-		transforms := buildLinkTransforms()
+		pipeline, err := buildPipeline()
+		cobra.CheckErr(err)
 		watcher.RunWatcher(
 			cmd.Context(),
 			dir,
 			patterns,
 			filterType,
-			transforms,
+			previewOptions(cmd),
+			pipeline,
 		)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(watchDirCmd)
+	addPreviewFlags(watchDirCmd)
+	addReportFlag(watchDirCmd)
 
 	watchDirCmd.Flags().StringSliceVarP(&patterns, "patterns", "p", []string{}, `File patterns to watch (comma-separated or multiple flags).
 Examples:
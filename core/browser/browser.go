@@ -0,0 +1,211 @@
+// Package browser cleans the URLs stored in the bookmark and history
+// databases of browser profiles: Firefox's places.sqlite and the History and
+// Bookmarks files of Chromium based browsers. The browser must be closed, as
+// it keeps its databases locked while running.
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/gkwa/littlewill/file"
+)
+
+// Store is a kind of browser database holding URLs
+type Store int
+
+const (
+	FirefoxPlaces     Store = iota // places.sqlite, history and bookmarks of Firefox
+	ChromiumHistory                // History, the history database of Chromium browsers
+	ChromiumBookmarks              // Bookmarks, the JSON bookmark file of Chromium browsers
+)
+
+var storeNames = map[Store]string{
+	FirefoxPlaces:     "firefox-places",
+	ChromiumHistory:   "chromium-history",
+	ChromiumBookmarks: "chromium-bookmarks",
+}
+
+func (s Store) String() string {
+	return storeNames[s]
+}
+
+// storeFiles are the names of the stores in a profile directory
+var storeFiles = map[string]Store{
+	"places.sqlite": FirefoxPlaces,
+	"History":       ChromiumHistory,
+	"Bookmarks":     ChromiumBookmarks,
+}
+
+// Change is a URL in a store that was cleaned, or would have been had it not
+// been skipped for the given reason
+type Change struct {
+	Location   string // the table and row, or the line, holding the URL
+	Original   string
+	Cleaned    string
+	Transforms []string
+	Skipped    string
+}
+
+// Result reports what cleaning a store did
+type Result struct {
+	Path    string
+	Store   Store
+	Backup  string // the copy of the store taken before changing it, if any
+	Changes []Change
+	// Bookmarks is the number of Firefox bookmarks whose place was cleaned
+	Bookmarks int64
+}
+
+// Updated returns the number of URLs that were cleaned
+func (r Result) Updated() int {
+	n := 0
+	for _, change := range r.Changes {
+		if change.Skipped == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Options controls how stores are cleaned
+type Options struct {
+	// DryRun reports what would change without writing anything or taking a
+	// backup
+	DryRun bool
+	// Now returns the time backups are named after. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (o Options) now() time.Time {
+	if o.Now == nil {
+		return time.Now()
+	}
+	return o.Now()
+}
+
+// FindStores returns the stores at path: the store itself for a file, or the
+// stores a profile directory holds
+func FindStores(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var stores []string
+	for _, name := range []string{"places.sqlite", "History", "Bookmarks"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			stores = append(stores, filepath.Join(path, name))
+		}
+	}
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("no places.sqlite, History or Bookmarks in %s", path)
+	}
+	return stores, nil
+}
+
+// DetectStore returns the kind of store at path, from its name and content
+func DetectStore(path string) (Store, error) {
+	content, err := readHead(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if file.DetectContent(path, content) == file.ChromeBookmarks {
+		return ChromiumBookmarks, nil
+	}
+	if string(content[:min(len(content), len(sqliteHeader))]) != sqliteHeader {
+		return 0, fmt.Errorf("%s is neither a SQLite database nor a Chromium Bookmarks file", path)
+	}
+	if store, ok := storeFiles[filepath.Base(path)]; ok && store != ChromiumBookmarks {
+		return store, nil
+	}
+	return detectDatabase(path)
+}
+
+// Clean cleans every URL in the store at path with the pipeline's URL
+// transforms. Unless opts asks for a dry run, the store is copied to a backup
+// next to it before anything is written, and database stores are updated in
+// a single transaction.
+func Clean(ctx context.Context, path string, pipeline core.Pipeline, opts Options) (Result, error) {
+	store, err := DetectStore(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Path: path, Store: store}
+	switch store {
+	case ChromiumBookmarks:
+		err = cleanBookmarksFile(&result, pipeline, opts)
+	default:
+		err = cleanDatabase(ctx, &result, pipeline.Engine, opts)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to clean %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// backupPath returns where the store at path is copied before it is changed
+func backupPath(path string, opts Options) string {
+	return path + ".littlewill-" + opts.now().Format("20060102-150405") + ".bak"
+}
+
+// cleanBookmarksFile cleans a Chromium Bookmarks file as a bookmark export
+func cleanBookmarksFile(result *Result, pipeline core.Pipeline, opts Options) error {
+	info, err := os.Stat(result.Path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(result.Path)
+	if err != nil {
+		return err
+	}
+
+	processed, changes, _, err := pipeline.ApplyBookmarks(links.ChromeBookmarks, content)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		result.Changes = append(result.Changes, Change{
+			Location:   fmt.Sprintf("line %d", change.Line),
+			Original:   change.Original,
+			Cleaned:    change.Cleaned,
+			Transforms: change.Transforms,
+		})
+	}
+	if opts.DryRun || len(changes) == 0 {
+		return nil
+	}
+
+	backup := backupPath(result.Path, opts)
+	if err := os.WriteFile(backup, content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to back up: %w", err)
+	}
+	result.Backup = backup
+	return os.WriteFile(result.Path, processed, info.Mode().Perm())
+}
+
+// readHead returns the first bytes of the file at path
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := f.Read(head)
+	if err != nil && n == 0 {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return head[:n], nil
+}
@@ -0,0 +1,292 @@
+package browser
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gkwa/littlewill/core"
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+)
+
+var backupTime = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// moveSite moves URLs on old.example.org to new.example.org
+var moveSite = links.NewTransform("move-site", "moving a site", func(host string) bool {
+	return host == "old.example.org"
+}, func(u *url.URL) {
+	u.Host = "new.example.org"
+})
+
+func testPipeline() core.Pipeline {
+	return core.NewPipeline([]links.Transform{links.GenericTracking, moveSite})
+}
+
+// loadFixture builds a database named name in a temporary directory from a
+// SQL fixture, filling in the url_hash of Firefox places
+func loadFixture(t *testing.T, fixture, name string) string {
+	t.Helper()
+	schema, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	db, err := openDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+
+	if fixture == "places.sql" {
+		for id, rawURL := range queryURLs(t, path, "moz_places") {
+			if _, err := db.Exec("UPDATE moz_places SET url_hash = ? WHERE id = ?", firefoxURLHash(rawURL), id); err != nil {
+				t.Fatalf("Failed to hash URL: %v", err)
+			}
+		}
+	}
+	return path
+}
+
+// queryURLs returns the URL of every row of table by id
+func queryURLs(t *testing.T, path, table string) map[int64]string {
+	t.Helper()
+	db, err := openDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, url FROM " + table)
+	if err != nil {
+		t.Fatalf("Failed to query %s: %v", table, err)
+	}
+	defer rows.Close()
+	urls := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var rawURL string
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			t.Fatalf("Failed to scan row: %v", err)
+		}
+		urls[id] = rawURL
+	}
+	return urls
+}
+
+func queryInt(t *testing.T, path, query string, args ...any) int64 {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	var n int64
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	return n
+}
+
+func TestCleanFirefoxPlaces(t *testing.T) {
+	path := loadFixture(t, "places.sql", "places.sqlite")
+	original := queryURLs(t, path, "moz_places")
+
+	result, err := Clean(context.Background(), path, testPipeline(), Options{Now: func() time.Time { return backupTime }})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedChanges := []Change{
+		{Location: "moz_places 1", Original: original[1], Cleaned: "https://example.com/a?id=1", Transforms: []string{"generic-tracking"}},
+		{Location: "moz_places 2", Original: original[2], Cleaned: "https://example.com/b", Transforms: []string{"generic-tracking"}, Skipped: "the cleaned URL is already moz_places 3"},
+		{Location: "moz_places 4", Original: original[4], Cleaned: "https://new.example.org/c", Transforms: []string{"generic-tracking", "move-site"}, Skipped: "cleaning moves it to another site"},
+	}
+	if diff := cmp.Diff(expectedChanges, result.Changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+	if result.Updated() != 1 || result.Bookmarks != 2 {
+		t.Errorf("Expected 1 place and 2 bookmarks updated, got %d and %d", result.Updated(), result.Bookmarks)
+	}
+
+	expected := withChanges(original, map[int64]string{1: "https://example.com/a?id=1"})
+	if diff := cmp.Diff(expected, queryURLs(t, path, "moz_places")); diff != "" {
+		t.Errorf("Unexpected places (-want +got):\n%s", diff)
+	}
+	if hash := queryInt(t, path, "SELECT url_hash FROM moz_places WHERE id = 1"); hash != firefoxURLHash("https://example.com/a?id=1") {
+		t.Errorf("url_hash was not updated, got %d", hash)
+	}
+	if n := queryInt(t, path, "SELECT count(*) FROM moz_bookmarks WHERE syncChangeCounter = 2"); n != 2 {
+		t.Errorf("Expected 2 bookmarks to be marked for sync, got %d", n)
+	}
+
+	if result.Backup != path+".littlewill-20260501-120000.bak" {
+		t.Errorf("Unexpected backup path %q", result.Backup)
+	}
+	if diff := cmp.Diff(original, queryURLs(t, result.Backup, "moz_places")); diff != "" {
+		t.Errorf("Backup differs from the original (-want +got):\n%s", diff)
+	}
+}
+
+func TestCleanFirefoxPlacesDryRun(t *testing.T) {
+	path := loadFixture(t, "places.sql", "places.sqlite")
+	original := queryURLs(t, path, "moz_places")
+
+	// the browser holding the write lock does not stop a dry run
+	db, err := openDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	result, err := Clean(context.Background(), path, testPipeline(), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedChanges := []Change{
+		{Location: "moz_places 1", Original: original[1], Cleaned: "https://example.com/a?id=1", Transforms: []string{"generic-tracking"}},
+		{Location: "moz_places 2", Original: original[2], Cleaned: "https://example.com/b", Transforms: []string{"generic-tracking"}, Skipped: "the cleaned URL is already moz_places 3"},
+		{Location: "moz_places 4", Original: original[4], Cleaned: "https://new.example.org/c", Transforms: []string{"generic-tracking", "move-site"}, Skipped: "cleaning moves it to another site"},
+	}
+	if diff := cmp.Diff(expectedChanges, result.Changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+	if result.Bookmarks != 2 || result.Backup != "" {
+		t.Errorf("Expected 2 bookmarks reported and no backup, got %d and %q", result.Bookmarks, result.Backup)
+	}
+	if diff := cmp.Diff(original, queryURLs(t, path, "moz_places")); diff != "" {
+		t.Errorf("Dry run changed places (-want +got):\n%s", diff)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Dry run left files behind: %v", entries)
+	}
+}
+
+func TestCleanFirefoxPlacesRefusesUnknownHashes(t *testing.T) {
+	path := loadFixture(t, "places.sql", "places.sqlite")
+	db, err := openDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec("UPDATE moz_places SET url_hash = 42 WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to update fixture: %v", err)
+	}
+	db.Close()
+	original := queryURLs(t, path, "moz_places")
+
+	if _, err := Clean(context.Background(), path, testPipeline(), Options{}); err == nil {
+		t.Fatal("Expected an error for a url_hash that does not match, got nil")
+	}
+	if diff := cmp.Diff(original, queryURLs(t, path, "moz_places")); diff != "" {
+		t.Errorf("Places changed despite the error (-want +got):\n%s", diff)
+	}
+}
+
+func TestCleanChromiumHistory(t *testing.T) {
+	path := loadFixture(t, "history.sql", "History")
+	original := queryURLs(t, path, "urls")
+
+	result, err := Clean(context.Background(), path, testPipeline(), Options{Now: func() time.Time { return backupTime }})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Store != ChromiumHistory {
+		t.Errorf("Expected %v, got %v", ChromiumHistory, result.Store)
+	}
+
+	expectedChanges := []Change{
+		{Location: "urls 1", Original: original[1], Cleaned: "https://example.com/a?id=1", Transforms: []string{"generic-tracking"}},
+		{Location: "urls 2", Original: original[2], Cleaned: "https://example.com/b", Transforms: []string{"generic-tracking"}, Skipped: "the cleaned URL is already urls 3"},
+	}
+	if diff := cmp.Diff(expectedChanges, result.Changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+
+	expected := withChanges(original, map[int64]string{1: "https://example.com/a?id=1"})
+	if diff := cmp.Diff(expected, queryURLs(t, path, "urls")); diff != "" {
+		t.Errorf("Unexpected URLs (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(original, queryURLs(t, result.Backup, "urls")); diff != "" {
+		t.Errorf("Backup differs from the original (-want +got):\n%s", diff)
+	}
+}
+
+func TestCleanChromiumBookmarks(t *testing.T) {
+	const bookmarks = `{
+   "checksum": "",
+   "roots": {
+      "bookmark_bar": {
+         "children": [ {
+            "id": "5",
+            "name": "A",
+            "type": "url",
+            "url": "https://example.com/a?utm_source=x"
+         } ],
+         "id": "1",
+         "name": "Bookmarks bar",
+         "type": "folder"
+      }
+   },
+   "version": 1
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Bookmarks")
+	if err := os.WriteFile(path, []byte(bookmarks), 0o600); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	stores, err := FindStores(dir)
+	if err != nil || len(stores) != 1 || stores[0] != path {
+		t.Fatalf("Expected to find %s, got %v, %v", path, stores, err)
+	}
+
+	result, err := Clean(context.Background(), path, testPipeline(), Options{Now: func() time.Time { return backupTime }})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Updated() != 1 || result.Changes[0].Location != "line 9" {
+		t.Errorf("Unexpected changes: %+v", result.Changes)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read bookmarks: %v", err)
+	}
+	if got := string(content); got == bookmarks || len(got) != len(bookmarks)-len("?utm_source=x") {
+		t.Errorf("Unexpected bookmarks:\n%s", got)
+	}
+	backup, err := os.ReadFile(result.Backup)
+	if err != nil || string(backup) != bookmarks {
+		t.Errorf("Backup differs from the original: %v", err)
+	}
+}
+
+// withChanges returns a copy of m with the entries of changes applied
+func withChanges(m, changes map[int64]string) map[int64]string {
+	result := make(map[int64]string, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	for k, v := range changes {
+		result[k] = v
+	}
+	return result
+}
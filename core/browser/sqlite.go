@@ -0,0 +1,302 @@
+package browser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gkwa/littlewill/core/links"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// openDatabase opens the SQLite database at path, waiting a little for locks
+// held by others
+func openDatabase(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(2000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// openDatabaseReadOnly opens the SQLite database at path without ever
+// writing to it, so that read-only files can be inspected too
+func openDatabaseReadOnly(path string) (*sql.DB, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite honors mode=ro in file: URIs only
+	uri := &url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: "mode=ro&_pragma=busy_timeout(2000)"}
+	db, err := sql.Open("sqlite", uri.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// dbtx runs statements on a database or within a transaction
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// detectDatabase tells Firefox and Chromium databases apart by their tables
+func detectDatabase(path string) (Store, error) {
+	db, err := openDatabase(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	for _, probe := range []struct {
+		table string
+		store Store
+	}{
+		{"moz_places", FirefoxPlaces},
+		{"urls", ChromiumHistory},
+	} {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", probe.table).Scan(&n)
+		if err != nil {
+			return 0, explainLocked(err)
+		}
+		if n > 0 {
+			return probe.store, nil
+		}
+	}
+	return 0, fmt.Errorf("%s has neither a moz_places nor a urls table", path)
+}
+
+// candidate is a row whose URL the transforms change
+type candidate struct {
+	id       int64
+	original string
+	cleaned  string
+	names    []string
+}
+
+// cleanDatabase cleans the URLs of a Firefox moz_places or Chromium urls
+// table. Rows are skipped when their cleaned URL is already stored in another
+// row, and in Firefox when cleaning moves the URL to another site, which
+// Firefox keeps track of in other tables. Firefox bookmarks of a cleaned place
+// have their sync change counter bumped so that Sync picks up the change.
+// A dry run opens the database read-only and only queries it.
+func cleanDatabase(ctx context.Context, result *Result, engine *links.Engine, opts Options) error {
+	open := openDatabase
+	if opts.DryRun {
+		open = openDatabaseReadOnly
+	}
+	db, err := open(result.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	firefox := result.Store == FirefoxPlaces
+	table := "urls"
+	if firefox {
+		table = "moz_places"
+	}
+
+	candidates, err := findCandidates(ctx, db, engine, firefox)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if opts.DryRun {
+		return updateRows(ctx, db, result, candidates, table, true)
+	}
+
+	backup := backupPath(result.Path, opts)
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", backup); err != nil {
+		return fmt.Errorf("failed to back up: %w", explainLocked(err))
+	}
+	result.Backup = backup
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", explainLocked(err))
+	}
+	defer tx.Rollback()
+
+	if err := updateRows(ctx, tx, result, candidates, table, false); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", explainLocked(err))
+	}
+	return nil
+}
+
+// updateRows stores the cleaned URLs of the candidates in table and records
+// the changes in result. A dry run records them without writing, keeping
+// track of the URLs the updates would add and remove instead.
+func updateRows(ctx context.Context, db dbtx, result *Result, candidates []candidate, table string, dryRun bool) error {
+	firefox := table == "moz_places"
+	// the rows of the URLs updated so far, and the URLs they no longer hold
+	moved := make(map[string]int64)
+	vacated := make(map[string]bool)
+
+	for _, c := range candidates {
+		change := Change{
+			Location:   fmt.Sprintf("%s %d", table, c.id),
+			Original:   c.original,
+			Cleaned:    c.cleaned,
+			Transforms: c.names,
+		}
+
+		if firefox && !sameSite(c.original, c.cleaned) {
+			change.Skipped = "cleaning moves it to another site"
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+
+		existing, found := moved[c.cleaned]
+		if !found && !vacated[c.cleaned] {
+			err := db.QueryRowContext(ctx, "SELECT id FROM "+table+" WHERE url = ? LIMIT 1", c.cleaned).Scan(&existing)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s: %w", change.Location, explainLocked(err))
+			}
+			found = err == nil
+		}
+		if found {
+			change.Skipped = fmt.Sprintf("the cleaned URL is already %s %d", table, existing)
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+
+		bookmarks, err := updateRow(ctx, db, c, firefox, dryRun)
+		if err != nil {
+			return fmt.Errorf("%s: %w", change.Location, err)
+		}
+		result.Bookmarks += bookmarks
+		delete(moved, c.original)
+		vacated[c.original] = true
+		moved[c.cleaned] = c.id
+		delete(vacated, c.cleaned)
+		result.Changes = append(result.Changes, change)
+	}
+	return nil
+}
+
+// updateRow stores the cleaned URL of a candidate, bumping the sync change
+// counter of Firefox bookmarks of it, and returns the number of those
+// bookmarks. A dry run only counts the bookmarks.
+func updateRow(ctx context.Context, db dbtx, c candidate, firefox, dryRun bool) (int64, error) {
+	switch {
+	case dryRun && firefox:
+		var n int64
+		err := db.QueryRowContext(ctx, "SELECT count(*) FROM moz_bookmarks WHERE fk = ?", c.id).Scan(&n)
+		return n, explainLocked(err)
+	case dryRun:
+		return 0, nil
+	case firefox:
+		_, err := db.ExecContext(ctx, "UPDATE moz_places SET url = ?, url_hash = ? WHERE id = ?", c.cleaned, firefoxURLHash(c.cleaned), c.id)
+		if err != nil {
+			return 0, err
+		}
+		res, err := db.ExecContext(ctx, "UPDATE moz_bookmarks SET syncChangeCounter = syncChangeCounter + 1 WHERE fk = ?", c.id)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	default:
+		_, err := db.ExecContext(ctx, "UPDATE urls SET url = ? WHERE id = ?", c.cleaned, c.id)
+		return 0, err
+	}
+}
+
+// findCandidates returns the rows whose URL the engine changes. Firefox rows
+// must carry the url_hash Firefox computes for their URL, or the hashes of
+// the cleaned URLs could not be trusted either.
+func findCandidates(ctx context.Context, db *sql.DB, engine *links.Engine, firefox bool) ([]candidate, error) {
+	query := "SELECT id, url, 0 FROM urls WHERE url IS NOT NULL"
+	if firefox {
+		query = "SELECT id, url, url_hash FROM moz_places WHERE url IS NOT NULL"
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, explainLocked(err)
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var hash int64
+		if err := rows.Scan(&c.id, &c.original, &hash); err != nil {
+			return nil, err
+		}
+		cleaned, names, err := engine.CleanURL(c.original)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", c.id, err)
+		}
+		if cleaned == c.original {
+			continue
+		}
+		if firefox && hash != firefoxURLHash(c.original) {
+			return nil, fmt.Errorf("moz_places %d: url_hash does not match its URL, refusing to write hashes that may be wrong", c.id)
+		}
+		c.cleaned = cleaned
+		c.names = names
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// sameSite reports whether two URLs share scheme and host
+func sameSite(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// firefoxURLHash returns the url_hash Firefox stores for a URL: the hash of
+// the scheme in the upper 16 of 48 bits, followed by the hash of the whole
+// URL. Strings without a scheme in their first 50 bytes hash as a whole.
+func firefoxURLHash(rawURL string) int64 {
+	head := rawURL[:min(len(rawURL), 50)]
+	if colon := strings.IndexByte(head, ':'); colon > 0 {
+		return int64(uint64(mozillaHash(rawURL[:colon])&0xFFFF)<<32 + uint64(mozillaHash(rawURL)))
+	}
+	return int64(mozillaHash(rawURL))
+}
+
+// mozillaHash is the string hash of Mozilla's HashFunctions.h
+func mozillaHash(s string) uint32 {
+	const goldenRatio = 0x9E3779B9
+	var h uint32
+	for i := 0; i < len(s); i++ {
+		h = goldenRatio * (bits.RotateLeft32(h, 5) ^ uint32(s[i]))
+	}
+	return h
+}
+
+// explainLocked adds a hint to errors caused by the browser holding the
+// database
+func explainLocked(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code()&0xFF == sqlite3.SQLITE_BUSY {
+		return fmt.Errorf("%w (is the browser still running?)", err)
+	}
+	return err
+}
@@ -0,0 +1,14 @@
+-- A Chromium History database reduced to the tables littlewill touches
+CREATE TABLE urls(id INTEGER PRIMARY KEY AUTOINCREMENT, url LONGVARCHAR, title LONGVARCHAR, visit_count INTEGER DEFAULT 0 NOT NULL, typed_count INTEGER DEFAULT 0 NOT NULL, last_visit_time INTEGER NOT NULL, hidden INTEGER DEFAULT 0 NOT NULL);
+CREATE INDEX urls_url_index ON urls (url);
+CREATE TABLE visits(id INTEGER PRIMARY KEY AUTOINCREMENT, url INTEGER NOT NULL, visit_time INTEGER NOT NULL, from_visit INTEGER, transition INTEGER DEFAULT 0 NOT NULL, segment_id INTEGER, visit_duration INTEGER DEFAULT 0 NOT NULL);
+
+INSERT INTO urls (id, url, title, visit_count, last_visit_time) VALUES
+  (1, 'https://example.com/a?utm_source=newsletter&id=1', 'A', 3, 13300000000000000),
+  (2, 'https://example.com/b?fbclid=abc', 'B', 1, 13300000000000001),
+  (3, 'https://example.com/b', 'B', 5, 13300000000000002),
+  (4, 'https://example.com/d', 'D', 2, 13300000000000003);
+
+INSERT INTO visits (id, url, visit_time) VALUES
+  (1, 1, 13300000000000000),
+  (2, 2, 13300000000000001);
@@ -0,0 +1,25 @@
+-- A Firefox places.sqlite reduced to the tables littlewill touches. url_hash
+-- is filled in by the tests.
+CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0, hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER, guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL, description TEXT, preview_image_url TEXT, site_name TEXT, origin_id INTEGER, recalc_frecency INTEGER NOT NULL DEFAULT 0, alt_frecency INTEGER, recalc_alt_frecency INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR, keyword_id INTEGER, folder_type TEXT, dateAdded INTEGER, lastModified INTEGER, guid TEXT, syncStatus INTEGER NOT NULL DEFAULT 0, syncChangeCounter INTEGER NOT NULL DEFAULT 1);
+CREATE TABLE moz_historyvisits (id INTEGER PRIMARY KEY, from_visit INTEGER, place_id INTEGER, visit_date INTEGER, visit_type INTEGER, session INTEGER, source INTEGER DEFAULT 0 NOT NULL, triggeringPlaceId INTEGER);
+CREATE UNIQUE INDEX moz_places_guid_uniqueindex ON moz_places (guid);
+CREATE INDEX moz_places_url_hashindex ON moz_places (url_hash);
+
+INSERT INTO moz_places (id, url, title, rev_host, visit_count, guid) VALUES
+  (1, 'https://example.com/a?utm_source=newsletter&id=1', 'A', 'moc.elpmaxe.', 3, 'place1aaaaaa'),
+  (2, 'https://example.com/b?fbclid=abc', 'B', 'moc.elpmaxe.', 1, 'place2aaaaaa'),
+  (3, 'https://example.com/b', 'B', 'moc.elpmaxe.', 5, 'place3aaaaaa'),
+  (4, 'https://old.example.org/c?utm_source=x', 'C', 'gro.elpmaxe.dlo.', 1, 'place4aaaaaa'),
+  (5, 'https://example.com/d', 'D', 'moc.elpmaxe.', 2, 'place5aaaaaa'),
+  (6, 'place:sort=8&maxResults=10', 'Recent', NULL, 0, 'place6aaaaaa');
+
+INSERT INTO moz_bookmarks (id, type, fk, parent, position, title, guid, syncChangeCounter) VALUES
+  (1, 2, NULL, 0, 0, '', 'root________', 1),
+  (2, 1, 1, 1, 0, 'A', 'bookmark1aaa', 1),
+  (3, 1, 1, 1, 1, 'A again', 'bookmark2aaa', 1),
+  (4, 1, 5, 1, 2, 'D', 'bookmark3aaa', 1);
+
+INSERT INTO moz_historyvisits (id, place_id, visit_date, visit_type) VALUES
+  (1, 1, 1700000000000000, 1),
+  (2, 2, 1700000000000001, 1);
@@ -9,14 +9,65 @@ import (
 	"os"
 
 	"github.com/gkwa/littlewill/file"
+	"github.com/gkwa/littlewill/internal/diff"
 	"github.com/go-logr/logr"
 )
 
+// Options controls what happens to a file once its transformed content is known.
+type Options struct {
+	// DryRun reports files that would change without writing them.
+	DryRun bool
+	// Diff prints a unified diff for each file that would change without writing it.
+	Diff bool
+	// Color enables colored diff output.
+	Color bool
+	// Out receives dry-run and diff output. Defaults to os.Stdout.
+	Out io.Writer
+	// Report, when set, receives a FileReport as a JSON line for each file
+	// with URL changes.
+	Report io.Writer
+}
+
+// Preview reports whether the file is left untouched.
+func (o Options) Preview() bool {
+	return o.DryRun || o.Diff
+}
+
+func (o Options) output() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+// ProcessFile runs transforms over the file at path as document transforms.
+// They apply to Markdown and plain text, which is cleaned as Markdown. Formats
+// with a mode of their own, such as HTML, JSON and Org, have no place for
+// them and are left alone, as are files that are not cleaned at all.
 func ProcessFile(
 	logger logr.Logger,
 	path string,
 	transforms ...func(io.Reader, io.Writer) error,
 ) error {
+	var pipeline Pipeline
+	for i, transform := range transforms {
+		pipeline.Document = append(pipeline.Document, NamedTransform{Name: fmt.Sprintf("transform %d", i+1), Func: transform})
+	}
+	_, err := ProcessFileWithOptions(logger, path, Options{}, pipeline)
+	return err
+}
+
+// ProcessFileWithOptions transforms the file at path and reports whether its
+// content changed. The kind of content is detected from the path and the
+// content, and selects how the pipeline is applied; files of kinds that are
+// not cleaned, such as binaries and scripts, are skipped. The file is only
+// written when opts does not ask for a preview.
+func ProcessFileWithOptions(
+	logger logr.Logger,
+	path string,
+	opts Options,
+	pipeline Pipeline,
+) (bool, error) {
 	// skip past symlinks
 	f := file.File{Path: path}
 
@@ -24,38 +75,90 @@ func ProcessFile(
 	if err != nil {
 		logger.Error(err, "Failed to check if path is symlink", "path", path)
 	}
-	logger.V(1).Info("file type check", "path", path, "type", f.FileType())
 
 	if isSymlink {
 		logger.V(1).Info("skipping symlink", "path", path)
-		return nil
+		return false, nil
 	}
 
 	// ok we get to files now
 	originalContent, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read original file: %w", err)
+		return false, fmt.Errorf("failed to read original file: %w", err)
 	}
 
-	processedContent, err := ApplyTransforms(originalContent, transforms...)
+	kind := file.DetectContent(path, originalContent)
+	logger.V(1).Info("file type check", "path", path, "type", kind)
+
+	process := pipeline.processor(kind)
+	if process == nil {
+		logger.V(1).Info("skipping file of unsupported type", "path", path, "type", kind)
+		return false, nil
+	}
+
+	processedContent, changes, err := process(originalContent)
 	if err != nil {
-		return fmt.Errorf("failed to process file: %w", err)
+		return false, fmt.Errorf("failed to process file: %w", err)
 	}
 
 	if bytes.Equal(originalContent, processedContent) {
 		logger.V(1).Info("File content unchanged, skipping write")
-		return nil
+		return false, nil
+	}
+
+	if opts.Report != nil && len(changes) > 0 {
+		err = writeReport(opts.Report, FileReport{Path: path, Changes: changes})
+		if err != nil {
+			return true, err
+		}
+	}
+
+	if opts.Preview() {
+		return true, Preview(path, originalContent, processedContent, opts)
 	}
 
 	err = os.WriteFile(path, processedContent, 0o644)
 	if err != nil {
-		return fmt.Errorf("failed to write processed content to file: %w", err)
+		return true, fmt.Errorf("failed to write processed content to file: %w", err)
 	}
 
 	logger.V(1).Info("Successfully processed and updated file")
+	return true, nil
+}
+
+// Preview writes a unified diff or a "would update" line for name to the
+// output configured in opts. Unchanged content produces no output.
+func Preview(name string, originalContent, processedContent []byte, opts Options) error {
+	if bytes.Equal(originalContent, processedContent) {
+		return nil
+	}
+
+	// buffer the whole report so concurrent callers don't interleave
+	var buf bytes.Buffer
+	if opts.Diff {
+		err := diff.Unified(&buf, name, name, originalContent, processedContent, opts.Color)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", name, err)
+		}
+	} else {
+		fmt.Fprintf(&buf, "would update %s\n", name)
+	}
+
+	_, err := opts.output().Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write preview: %w", err)
+	}
 	return nil
 }
 
+// Summary records the outcome of processing a batch of paths.
+type Summary struct {
+	// Changed lists paths whose content was, or in preview mode would be, changed.
+	Changed []string
+	// Failed lists paths that could not be read, processed or written.
+	Failed []string
+}
+
 func ReadPathsFromStdin() ([]string, error) {
 	var paths []string
 	scanner := bufio.NewScanner(os.Stdin)
@@ -65,27 +168,34 @@ func ReadPathsFromStdin() ([]string, error) {
 	return paths, scanner.Err()
 }
 
-func ProcessPaths(ctx context.Context, paths []string, transforms ...func(io.Reader, io.Writer) error) {
+func ProcessPaths(ctx context.Context, paths []string, opts Options, pipeline Pipeline) Summary {
 	logger := logr.FromContextOrDiscard(ctx)
 
+	var summary Summary
 	for _, path := range paths {
 		logger.V(1).Info("Processing path", "path", path)
-		err := ProcessFile(logger, path, transforms...)
+		changed, err := ProcessFileWithOptions(logger, path, opts, pipeline)
 		if err != nil {
 			logger.Error(err, "Failed to process file", "path", path)
+			summary.Failed = append(summary.Failed, path)
+			continue
+		}
+		if changed {
+			summary.Changed = append(summary.Changed, path)
 		}
 	}
+	return summary
 }
 
-func ProcessPathsFromStdin(ctx context.Context, transforms ...func(io.Reader, io.Writer) error) {
+func ProcessPathsFromStdin(ctx context.Context, opts Options, pipeline Pipeline) (Summary, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	logger.V(1).Info("Processing paths from stdin")
 
 	paths, err := ReadPathsFromStdin()
 	if err != nil {
 		logger.Error(err, "Error reading input")
-		return
+		return Summary{}, err
 	}
 
-	ProcessPaths(ctx, paths, transforms...)
+	return ProcessPaths(ctx, paths, opts, pipeline), nil
 }
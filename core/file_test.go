@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/go-logr/logr"
+)
+
+func TestProcessFileWithOptionsPreview(t *testing.T) {
+	const original = "Search: https://www.google.com/search?q=test&hl=en\n"
+
+	testCases := []struct {
+		name        string
+		opts        Options
+		contains    []string
+		fileChanged bool
+	}{
+		{
+			name:     "Dry run reports the file without writing it",
+			opts:     Options{DryRun: true},
+			contains: []string{"would update "},
+		},
+		{
+			name: "Diff prints a unified diff without writing the file",
+			opts: Options{Diff: true},
+			contains: []string{
+				"-Search: https://www.google.com/search?q=test&hl=en",
+				"+Search: https://www.google.com/search?q=test",
+				"@@ -1 +1 @@",
+			},
+		},
+		{
+			name:        "No preview writes the file",
+			opts:        Options{},
+			fileChanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "notes.md")
+			if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			var out bytes.Buffer
+			tc.opts.Out = &out
+
+			changed, err := ProcessFileWithOptions(logr.Discard(), path, tc.opts, NewPipeline([]links.Transform{links.Google}))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !changed {
+				t.Error("Expected the file to be reported as changed")
+			}
+
+			for _, want := range tc.contains {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if got := string(content) != original; got != tc.fileChanged {
+				t.Errorf("File changed = %v, want %v", got, tc.fileChanged)
+			}
+		})
+	}
+}
+
+func TestProcessFileTextFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("See https://example.com/?utm_source=x\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := ProcessFile(logr.Discard(), path, links.RemoveGenericTrackingParams); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if expected := "See https://example.com/\n"; string(content) != expected {
+		t.Errorf("File content = %q, want %q", content, expected)
+	}
+}
@@ -34,6 +34,11 @@ func TestRemoveParamsFromAmazonURLs(t *testing.T) {
 			input:    "https://www.amazon.com/s?k=laptop&crid=ABC123&sprefix=lap%2Caps%2C123&ref=nb_sb_noss_1",
 			expected: "https://www.amazon.com/s?k=laptop",
 		},
+		{
+			name:     "Amazon search keeps only known parameters",
+			input:    "https://www.amazon.com/s?k=kettle&i=kitchen&dc&ds=v1%3Aabc&rnid=123",
+			expected: "https://www.amazon.com/s?i=kitchen&k=kettle",
+		},
 		{
 			name:     "Amazon URL with pd_ parameters",
 			input:    "https://www.amazon.com/dp/B08N5WRWNW?pd_rd_i=B08N5WRWNW&pd_rd_r=abc-123&pd_rd_w=xyz&pd_rd_wg=def-456",
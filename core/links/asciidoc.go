@@ -0,0 +1,153 @@
+package links
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+)
+
+var (
+	// asciiDocLinkRegex matches a URL macro, url[text] or link:url[text]. The
+	// first group is the URL.
+	asciiDocLinkRegex = regexp.MustCompile(`(?:link:)?((?:https?|ftp|irc)://[^\s\[\]<>]+|mailto:[^\s\[\]<>]+)\[[^\]\n]*\]`)
+	// asciiDocLiteralStyleRegex matches a block attribute line that makes the
+	// block after it a listing or literal, such as [source,sh]
+	asciiDocLiteralStyleRegex = regexp.MustCompile(`^\[(?:source|listing|literal)(?:[,#.%][^\]]*)?\]$`)
+)
+
+// asciiDocLiteralRanges returns the sorted ranges of an AsciiDoc document
+// whose content is not processed as prose: listing (----), literal (....),
+// passthrough (++++) and comment (////) blocks, fenced code blocks (```),
+// paragraphs styled [source], [listing] or [literal] without delimiters and
+// `monospace` text. A block is closed by a line identical to its opening
+// delimiter; an unterminated block runs to the end of the document.
+func asciiDocLiteralRanges(content []byte) []byteRange {
+	var ranges []byteRange
+	var delimiter []byte
+	blockStart := 0
+	for lineStart := 0; lineStart < len(content); {
+		lineStop := endOfLine(content, lineStart)
+		line := bytes.TrimRight(content[lineStart:lineStop], " \t\r")
+
+		if delimiter == nil {
+			if bytes.HasPrefix(line, []byte("```")) {
+				delimiter = []byte("```")
+				blockStart = lineStart
+			} else if isAsciiDocDelimiter(line) {
+				delimiter = line
+				blockStart = lineStart
+			} else if asciiDocLiteralStyleRegex.Match(line) {
+				if stop := asciiDocParagraphEnd(content, lineStop); stop > lineStop {
+					ranges = append(ranges, byteRange{lineStop + 1, stop})
+					lineStop = stop
+				}
+			}
+		} else if bytes.Equal(line, delimiter) {
+			ranges = append(ranges, byteRange{blockStart, lineStop})
+			delimiter = nil
+		}
+
+		lineStart = lineStop + 1
+	}
+	if delimiter != nil {
+		ranges = append(ranges, byteRange{blockStart, len(content)})
+	}
+
+	blocks := ranges
+	next := 0
+	for i := 0; i < len(content); i++ {
+		stop := asciiDocMonospaceEnd(content, i)
+		if stop < 0 || overlaps(blocks, &next, i, i+1) {
+			continue
+		}
+		ranges = append(ranges, byteRange{i, stop})
+		i = stop - 1
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges
+}
+
+// asciiDocParagraphEnd returns the end of the paragraph that follows the
+// line ending at offset lineStop, or lineStop when a blank line, the end of
+// the document or a block delimiter follows instead
+func asciiDocParagraphEnd(content []byte, lineStop int) int {
+	end := lineStop
+	for lineStart := lineStop + 1; lineStart < len(content); {
+		stop := endOfLine(content, lineStart)
+		line := bytes.TrimRight(content[lineStart:stop], " \t\r")
+		if len(line) == 0 || (end == lineStop && (isAsciiDocDelimiter(line) || bytes.HasPrefix(line, []byte("```")))) {
+			break
+		}
+		end = stop
+		lineStart = stop + 1
+	}
+	return end
+}
+
+// asciiDocMonospaceEnd returns the end of the monospace text that starts at
+// offset start, or -1 if there is none. Unconstrained monospace, between
+// double backticks, may start anywhere; constrained `text` must not be next
+// to a word character on the outside or to whitespace on the inside. Neither
+// spans a blank line.
+func asciiDocMonospaceEnd(content []byte, start int) int {
+	if content[start] != '`' {
+		return -1
+	}
+	unconstrained := start+1 < len(content) && content[start+1] == '`'
+	if !unconstrained && (start > 0 && isWordByte(content[start-1]) ||
+		start+1 == len(content) || isSpaceByte(content[start+1])) {
+		return -1
+	}
+
+	for i := start + 1; i < len(content); i++ {
+		switch {
+		case unconstrained:
+			if i > start+3 && content[i] == '`' && content[i-1] == '`' {
+				return i + 1
+			}
+		case content[i] == '`' && !isSpaceByte(content[i-1]) && (i+1 == len(content) || !isWordByte(content[i+1])):
+			return i + 1
+		}
+		if content[i] == '\n' && len(bytes.TrimLeft(content[i+1:endOfLine(content, i+1)], " \t\r")) == 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// isAsciiDocDelimiter reports whether line delimits a listing, literal,
+// passthrough or comment block: four or more of the same one of - . + /
+func isAsciiDocDelimiter(line []byte) bool {
+	if len(line) < 4 || bytes.IndexByte([]byte("-.+/"), line[0]) < 0 {
+		return false
+	}
+	return len(bytes.Trim(line, string(line[0]))) == 0
+}
+
+// FindAsciiDocURLs returns every URL in an AsciiDoc document, in document
+// order. The URLs of url[text] and link:url[text] macros are found from the
+// macro syntax, so that the link text is not taken as part of the URL; other
+// URLs are found in the remaining text. URLs in listing, literal, passthrough
+// and comment blocks, source paragraphs and monospace text are skipped.
+func FindAsciiDocURLs(content []byte) []URLSite {
+	m := strictURLMatcher()
+	skip := asciiDocLiteralRanges(content)
+
+	var targets []URLSite
+	next := 0
+	for _, loc := range asciiDocLinkRegex.FindAllSubmatchIndex(content, -1) {
+		if overlaps(skip, &next, loc[0], loc[1]) {
+			continue
+		}
+		target := string(content[loc[2]:loc[3]])
+		if locs := m.findAll(target); len(locs) == 0 || locs[0][0] != 0 {
+			continue
+		}
+		targets = append(targets, URLSite{URL: target, Offset: loc[2], Syntax: SyntaxAsciiDocLink})
+	}
+
+	return withBareURLs(content, skip, targets)
+}
@@ -0,0 +1,102 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteAsciiDoc(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "URL macro",
+			input:    "See https://example.com/a?utm_source=x&id=1[the page].\n",
+			expected: "See https://example.com/a?id=1[the page].\n",
+		},
+		{
+			name:     "Link macro",
+			input:    "link:https://example.com/?utm_source=x[page,window=_blank]\n",
+			expected: "link:https://example.com/[page,window=_blank]\n",
+		},
+		{
+			name:     "Bare URL and attribute entry",
+			input:    ":homepage: https://example.com/a?utm_source=x\n\nhttps://example.com/b?utm_source=y\n",
+			expected: ":homepage: https://example.com/a\n\nhttps://example.com/b\n",
+		},
+		{
+			name: "Listing block",
+			input: "[source,sh]\n----\ncurl https://example.com/?utm_source=x\n----\n" +
+				"https://example.com/?utm_source=y[y]\n",
+			expected: "[source,sh]\n----\ncurl https://example.com/?utm_source=x\n----\n" +
+				"https://example.com/[y]\n",
+		},
+		{
+			name:     "Literal block with a longer delimiter",
+			input:    "......\n....\nhttps://example.com/?utm_source=x\n......\n",
+			expected: "......\n....\nhttps://example.com/?utm_source=x\n......\n",
+		},
+		{
+			name:     "Comment and passthrough blocks",
+			input:    "////\nhttps://example.com/?utm_source=x\n////\n++++\n<a href=\"https://example.com/?utm_source=y\">\n++++\n",
+			expected: "////\nhttps://example.com/?utm_source=x\n////\n++++\n<a href=\"https://example.com/?utm_source=y\">\n++++\n",
+		},
+		{
+			name:     "Monospace text",
+			input:    "Run `curl https://example.com/?utm_source=x` or ``https://example.com/?utm_source=y``s, not https://example.com/?utm_source=z\n",
+			expected: "Run `curl https://example.com/?utm_source=x` or ``https://example.com/?utm_source=y``s, not https://example.com/\n",
+		},
+		{
+			name:     "Backticks inside words and across paragraphs are not monospace",
+			input:    "it`s https://example.com/?utm_source=x\n\n` https://example.com/?utm_source=y `\n",
+			expected: "it`s https://example.com/\n\n` https://example.com/ `\n",
+		},
+		{
+			name: "Source paragraph without delimiters",
+			input: "[source,sh]\ncurl https://example.com/?utm_source=x\n  | jq .\n\n" +
+				"https://example.com/?utm_source=y\n",
+			expected: "[source,sh]\ncurl https://example.com/?utm_source=x\n  | jq .\n\n" +
+				"https://example.com/\n",
+		},
+		{
+			name:     "Literal paragraph",
+			input:    "[literal]\nhttps://example.com/?utm_source=x\n",
+			expected: "[literal]\nhttps://example.com/?utm_source=x\n",
+		},
+		{
+			name:     "Fenced code block",
+			input:    "```sh\ncurl https://example.com/?utm_source=x\n```\n",
+			expected: "```sh\ncurl https://example.com/?utm_source=x\n```\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), AsciiDocURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindAsciiDocURLs(t *testing.T) {
+	content := "https://a.example/x[a]\nsee https://b.example/\n"
+
+	expected := []URLSite{
+		{URL: "https://a.example/x", Offset: 0, Line: 1, Column: 1, Syntax: SyntaxAsciiDocLink},
+		{URL: "https://b.example/", Offset: 27, Line: 2, Column: 5, Syntax: SyntaxBare},
+	}
+
+	result := FindAsciiDocURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
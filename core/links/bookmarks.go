@@ -0,0 +1,384 @@
+package links
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"unicode/utf16"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// BookmarkFormat is a format browsers export bookmarks in
+type BookmarkFormat int
+
+const (
+	NetscapeBookmarks BookmarkFormat = iota // the bookmarks.html every browser exports
+	ChromeBookmarks                         // the Bookmarks JSON file of Chrome and other Chromium browsers
+	FirefoxBookmarks                        // the bookmarks-*.json backups of Firefox
+)
+
+var bookmarkFormatNames = map[BookmarkFormat]string{
+	NetscapeBookmarks: "netscape",
+	ChromeBookmarks:   "chrome",
+	FirefoxBookmarks:  "firefox",
+}
+
+func (f BookmarkFormat) String() string {
+	return bookmarkFormatNames[f]
+}
+
+// Bookmark is a bookmark in an export. The site is the bookmark's URL as it
+// is written in the export.
+type Bookmark struct {
+	Title string
+	Site  URLSite
+	list  *bookmarkList
+	index int
+}
+
+// BookmarkDuplicate is a bookmark whose URL, once cleaned, is the same as the
+// cleaned URL of an earlier bookmark even though the original URLs differ.
+type BookmarkDuplicate struct {
+	Bookmark Bookmark
+	Of       Bookmark
+	URL      string // the cleaned URL both share
+}
+
+// bookmarkList holds the entries of a folder, as byte ranges, in the order
+// they appear. The entries of a JSON array are separated by commas, which
+// removing entries must keep valid.
+type bookmarkList struct {
+	entries   []byteRange
+	separated bool
+}
+
+// FindBookmarks returns the bookmarks of an export in document order.
+// Bookmarks whose address is not a URL, such as bookmarklets and Firefox
+// place: queries, are left out.
+func FindBookmarks(format BookmarkFormat, content []byte) ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	switch format {
+	case NetscapeBookmarks:
+		bookmarks = findNetscapeBookmarks(content)
+	case ChromeBookmarks, FirefoxBookmarks:
+		var err error
+		bookmarks, err = findJSONBookmarks(format, content)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown bookmark format %d", format)
+	}
+
+	sites := make([]URLSite, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		sites[i] = bookmark.Site
+	}
+	setLineColumns(content, sites)
+	for i := range bookmarks {
+		bookmarks[i].Site = sites[i]
+	}
+	return bookmarks, nil
+}
+
+// findNetscapeBookmarks returns the <A HREF> bookmarks of a Netscape bookmark
+// file. Each entry runs from the start of the line of its <DT> to the next
+// <DT>, <DL> or </DL> line, so that it includes any <DD> description.
+func findNetscapeBookmarks(content []byte) []Bookmark {
+	m := strictURLMatcher()
+	list := &bookmarkList{}
+	var bookmarks []Bookmark
+	var current *Bookmark
+
+	z := nethtml.NewTokenizer(bytes.NewReader(content))
+	offset := 0
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		name, _ := z.TagName()
+		switch {
+		case tt == nethtml.StartTagToken && string(name) == "a":
+			current = nil
+			for _, attr := range tagAttributes(raw) {
+				if attr.name != "href" {
+					continue
+				}
+				start, stop := trimHTMLSpace(content, offset+attr.valueStart, offset+attr.valueStop)
+				site := URLSite{URL: string(content[start:stop]), Offset: start, Syntax: SyntaxHTMLAttribute}
+				if locs := m.findAll(decodedURL(site)); len(locs) == 0 || locs[0][0] != 0 {
+					break
+				}
+				list.entries = append(list.entries, byteRange{
+					start: bytes.LastIndexByte(content[:offset], '\n') + 1,
+					stop:  netscapeEntryEnd(content, offset),
+				})
+				bookmarks = append(bookmarks, Bookmark{Site: site, list: list, index: len(list.entries) - 1})
+				current = &bookmarks[len(bookmarks)-1]
+			}
+		case tt == nethtml.TextToken && current != nil:
+			current.Title += string(z.Text())
+		case tt == nethtml.EndTagToken && string(name) == "a":
+			current = nil
+		}
+		offset += len(raw)
+	}
+	return bookmarks
+}
+
+// netscapeEntryEnd returns the start of the line that follows the entry whose
+// tag is at offset i
+func netscapeEntryEnd(content []byte, i int) int {
+	for lineStart := endOfLine(content, i) + 1; lineStart < len(content); {
+		lineStop := endOfLine(content, lineStart)
+		line := bytes.ToUpper(bytes.TrimSpace(content[lineStart:lineStop]))
+		for _, tag := range []string{"<DT>", "<DL>", "</DL>"} {
+			if bytes.HasPrefix(line, []byte(tag)) {
+				return lineStart
+			}
+		}
+		lineStart = lineStop + 1
+	}
+	return len(content)
+}
+
+// findJSONBookmarks returns the bookmarks of a Chrome Bookmarks file or a
+// Firefox JSON backup. Both nest folders through children arrays; Chrome
+// keeps a bookmark's address in url and its title in name, Firefox in uri
+// and title.
+func findJSONBookmarks(format BookmarkFormat, content []byte) ([]Bookmark, error) {
+	root, err := parseJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != '{' {
+		return nil, fmt.Errorf("not a %s bookmark file: not an object", format)
+	}
+
+	urlKey, titleKey := "uri", "title"
+	folders := []*jsonValue{root}
+	if format == ChromeBookmarks {
+		urlKey, titleKey = "url", "name"
+		roots := root.member("roots")
+		if roots == nil || roots.kind != '{' {
+			return nil, fmt.Errorf("not a %s bookmark file: no roots", format)
+		}
+		folders = nil
+		for _, m := range roots.members {
+			folders = append(folders, m.value)
+		}
+	}
+
+	m := strictURLMatcher()
+	var bookmarks []Bookmark
+	var walk func(folder *jsonValue)
+	walk = func(folder *jsonValue) {
+		children := folder.member("children")
+		if folder.kind != '{' || children == nil || children.kind != '[' {
+			return
+		}
+		list := &bookmarkList{separated: true}
+		for _, child := range children.elems {
+			list.entries = append(list.entries, byteRange{child.start, child.stop})
+		}
+		for i, child := range children.elems {
+			address := child.member(urlKey)
+			if address == nil || address.kind != '"' {
+				walk(child)
+				continue
+			}
+			if locs := m.findAll(address.str); len(locs) == 0 || locs[0][0] != 0 {
+				continue
+			}
+			bookmarks = append(bookmarks, Bookmark{
+				Title: child.memberString(titleKey),
+				Site:  URLSite{URL: string(content[address.start+1 : address.stop-1]), Offset: address.start + 1, Syntax: SyntaxEscapedString},
+				list:  list,
+				index: i,
+			})
+		}
+	}
+	for _, folder := range folders {
+		walk(folder)
+	}
+	return bookmarks, nil
+}
+
+// RewriteBookmarks returns a bookmark export with the URL of every bookmark
+// cleaned and everything else left byte for byte as it was, along with the
+// URLs that changed and the bookmarks that became duplicates of earlier ones.
+// With merge set, those duplicates are removed. The checksum of a Chrome
+// Bookmarks file is updated, provided the original one was valid.
+func (e *Engine) RewriteBookmarks(format BookmarkFormat, content []byte, merge bool) ([]byte, []Change, []BookmarkDuplicate, error) {
+	bookmarks, err := FindBookmarks(format, content)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var changes []Change
+	var edits []edit
+	var duplicates []BookmarkDuplicate
+	first := make(map[string]Bookmark)
+	for _, bookmark := range bookmarks {
+		site := bookmark.Site
+		cleaned, names, err := e.cleanSite(site)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("line %d, column %d: %w", site.Line, site.Column, err)
+		}
+		if cleaned != site.URL {
+			changes = append(changes, Change{Site: site, Cleaned: cleaned, Transforms: names})
+			edits = append(edits, edit{site.Offset, site.Offset + len(site.URL), cleaned})
+		}
+
+		key := decodedURL(URLSite{URL: cleaned, Syntax: site.Syntax})
+		earlier, ok := first[key]
+		if !ok {
+			first[key] = bookmark
+		} else if decodedURL(earlier.Site) != decodedURL(site) {
+			duplicates = append(duplicates, BookmarkDuplicate{Bookmark: bookmark, Of: earlier, URL: key})
+		}
+	}
+
+	if merge && len(duplicates) > 0 {
+		var removed []byteRange
+		for _, duplicate := range duplicates {
+			removed = append(removed, duplicate.Bookmark.list.entries[duplicate.Bookmark.index])
+		}
+		within := func(start int) bool {
+			return slices.ContainsFunc(removed, func(r byteRange) bool {
+				return start >= r.start && start < r.stop
+			})
+		}
+		edits = slices.DeleteFunc(edits, func(ed edit) bool { return within(ed.start) })
+		changes = slices.DeleteFunc(changes, func(c Change) bool { return within(c.Site.Offset) })
+		edits = append(edits, removalEdits(duplicates)...)
+	}
+
+	if len(edits) == 0 {
+		return content, nil, duplicates, nil
+	}
+	rewritten := applyEdits(content, edits)
+	if format == ChromeBookmarks {
+		rewritten, err = updateChromeChecksum(content, rewritten)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return rewritten, changes, duplicates, nil
+}
+
+// removalEdits returns the edits that remove the duplicates from their lists
+func removalEdits(duplicates []BookmarkDuplicate) []edit {
+	removed := make(map[*bookmarkList]map[int]bool)
+	for _, duplicate := range duplicates {
+		list := duplicate.Bookmark.list
+		if removed[list] == nil {
+			removed[list] = make(map[int]bool)
+		}
+		removed[list][duplicate.Bookmark.index] = true
+	}
+
+	var edits []edit
+	for list, indexes := range removed {
+		edits = append(edits, list.remove(indexes)...)
+	}
+	return edits
+}
+
+// remove returns the edits that remove the entries at indexes. Entries of a
+// separated list take the separator that follows them along, except at the
+// end of the list, where the separator before them goes instead.
+func (l *bookmarkList) remove(indexes map[int]bool) []edit {
+	var edits []edit
+	if !l.separated {
+		for i := range indexes {
+			edits = append(edits, edit{l.entries[i].start, l.entries[i].stop, ""})
+		}
+		return edits
+	}
+
+	lastKept := -1
+	for i := range l.entries {
+		if !indexes[i] {
+			lastKept = i
+		}
+	}
+	for i := 0; i < lastKept; i++ {
+		if indexes[i] {
+			edits = append(edits, edit{l.entries[i].start, l.entries[i+1].start, ""})
+		}
+	}
+	if last := len(l.entries) - 1; lastKept < last {
+		start := l.entries[0].start
+		if lastKept >= 0 {
+			start = l.entries[lastKept].stop
+		}
+		edits = append(edits, edit{start, l.entries[last].stop, ""})
+	}
+	return edits
+}
+
+// updateChromeChecksum replaces the checksum of a rewritten Chrome Bookmarks
+// file. Chrome checks it when loading the file; a checksum that did not match
+// the original is left alone, as it was not one this code can reproduce.
+func updateChromeChecksum(original, rewritten []byte) ([]byte, error) {
+	root, err := parseJSON(original)
+	if err != nil {
+		return nil, err
+	}
+	if stored := root.member("checksum"); stored == nil || stored.kind != '"' || stored.str != chromeChecksum(root) {
+		return rewritten, nil
+	}
+
+	root, err = parseJSON(rewritten)
+	if err != nil {
+		return nil, fmt.Errorf("rewritten bookmarks are not valid JSON: %w", err)
+	}
+	stored := root.member("checksum")
+	return applyEdits(rewritten, []edit{{stored.start + 1, stored.stop - 1, chromeChecksum(root)}}), nil
+}
+
+// chromeChecksum returns the checksum of a Chrome Bookmarks file: the MD5 of
+// the id, UTF-16 title and type of every node, and the URL of every bookmark,
+// walking the bookmark bar, other and mobile folders in that order.
+func chromeChecksum(root *jsonValue) string {
+	h := md5.New()
+	var walk func(node *jsonValue)
+	walk = func(node *jsonValue) {
+		h.Write([]byte(node.memberString("id")))
+		var title []byte
+		for _, unit := range utf16.Encode([]rune(node.memberString("name"))) {
+			title = binary.LittleEndian.AppendUint16(title, unit)
+		}
+		h.Write(title)
+
+		if node.memberString("type") == "url" {
+			h.Write([]byte("url"))
+			h.Write([]byte(node.memberString("url")))
+			return
+		}
+		h.Write([]byte("folder"))
+		if children := node.member("children"); children != nil {
+			for _, child := range children.elems {
+				if child.kind == '{' {
+					walk(child)
+				}
+			}
+		}
+	}
+
+	if roots := root.member("roots"); roots != nil {
+		for _, name := range []string{"bookmark_bar", "other", "synced"} {
+			if folder := roots.member(name); folder != nil && folder.kind == '{' {
+				walk(folder)
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,243 @@
+package links
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const netscapeExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1700000000">Reading</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/a?id=1&amp;utm_source=x" ADD_DATE="1700000001" ICON_URI="https://example.com/favicon.ico?utm_source=y">A &amp; B</A>
+        <DT><A HREF="https://example.com/a?id=1&amp;fbclid=abc" ADD_DATE="1700000002">A again</A>
+        <DD>Found it twice
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+    </DL><p>
+</DL><p>
+`
+
+func TestRewriteNetscapeBookmarks(t *testing.T) {
+	engine := NewEngine(GenericTracking)
+
+	result, changes, duplicates, err := engine.RewriteBookmarks(NetscapeBookmarks, []byte(netscapeExport), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := strings.NewReplacer(
+		"?id=1&amp;utm_source=x", "?id=1",
+		"?id=1&amp;fbclid=abc", "?id=1",
+	).Replace(netscapeExport)
+	if diff := cmp.Diff(expected, string(result)); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %d", len(changes))
+	}
+
+	if len(duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate, got %d", len(duplicates))
+	}
+	duplicate := duplicates[0]
+	got := []any{duplicate.Bookmark.Title, duplicate.Bookmark.Site.Line, duplicate.Of.Title, duplicate.Of.Site.Line, duplicate.URL}
+	want := []any{"A again", 9, "A & B", 8, "https://example.com/a?id=1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected duplicate (-want +got):\n%s", diff)
+	}
+
+	merged, _, _, err := engine.RewriteBookmarks(NetscapeBookmarks, []byte(netscapeExport), true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = strings.NewReplacer(
+		"?id=1&amp;utm_source=x", "?id=1",
+		"        <DT><A HREF=\"https://example.com/a?id=1&amp;fbclid=abc\" ADD_DATE=\"1700000002\">A again</A>\n        <DD>Found it twice\n", "",
+	).Replace(netscapeExport)
+	if diff := cmp.Diff(expected, string(merged)); diff != "" {
+		t.Errorf("Unexpected merged result (-want +got):\n%s", diff)
+	}
+}
+
+const chromeExport = `{
+   "checksum": "%s",
+   "roots": {
+      "bookmark_bar": {
+         "children": [ {
+            "date_added": "13300000000000000",
+            "id": "5",
+            "name": "Example",
+            "type": "url",
+            "url": "https://example.com/?utm_source=x"
+         }, {
+            "date_added": "13300000000000001",
+            "id": "6",
+            "name": "Example again",
+            "type": "url",
+            "url": "https://example.com/"
+         } ],
+         "id": "1",
+         "name": "Bookmarks bar",
+         "type": "folder"
+      },
+      "other": {
+         "children": [  ],
+         "id": "2",
+         "name": "Other bookmarks",
+         "type": "folder"
+      },
+      "synced": {
+         "children": [  ],
+         "id": "3",
+         "name": "Mobile bookmarks",
+         "type": "folder"
+      }
+   },
+   "version": 1
+}
+`
+
+// withChromeChecksum returns a Chrome export with a valid checksum
+func withChromeChecksum(t *testing.T, export string) string {
+	t.Helper()
+	root, err := parseJSON([]byte(strings.Replace(export, "%s", "", 1)))
+	if err != nil {
+		t.Fatalf("Invalid fixture: %v", err)
+	}
+	return strings.Replace(export, "%s", chromeChecksum(root), 1)
+}
+
+func TestRewriteChromeBookmarks(t *testing.T) {
+	engine := NewEngine(GenericTracking)
+	input := withChromeChecksum(t, chromeExport)
+
+	result, _, duplicates, err := engine.RewriteBookmarks(ChromeBookmarks, []byte(input), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cleaned := strings.Replace(chromeExport, "https://example.com/?utm_source=x", "https://example.com/", 1)
+	if diff := cmp.Diff(withChromeChecksum(t, cleaned), string(result)); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+	if len(duplicates) != 1 || duplicates[0].Bookmark.Title != "Example again" || duplicates[0].Of.Title != "Example" {
+		t.Errorf("Unexpected duplicates: %+v", duplicates)
+	}
+
+	merged, _, _, err := engine.RewriteBookmarks(ChromeBookmarks, []byte(input), true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !json.Valid(merged) {
+		t.Fatalf("Merged bookmarks are not valid JSON:\n%s", merged)
+	}
+	if strings.Contains(string(merged), "Example again") {
+		t.Errorf("Duplicate was not removed:\n%s", merged)
+	}
+	root, _ := parseJSON(merged)
+	if stored := root.memberString("checksum"); stored != chromeChecksum(root) {
+		t.Errorf("Checksum %s does not match the merged bookmarks", stored)
+	}
+}
+
+func TestRewriteChromeBookmarksKeepsUnknownChecksum(t *testing.T) {
+	input := strings.Replace(chromeExport, "%s", "0123456789abcdef0123456789abcdef", 1)
+
+	result, _, _, err := NewEngine(GenericTracking).RewriteBookmarks(ChromeBookmarks, []byte(input), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := strings.Replace(input, "https://example.com/?utm_source=x", "https://example.com/", 1)
+	if diff := cmp.Diff(expected, string(result)); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewriteFirefoxBookmarks(t *testing.T) {
+	input := `{"guid":"root________","title":"","type":"text/x-moz-place-container","root":"placesRoot","children":[` +
+		`{"guid":"menu________","title":"menu","type":"text/x-moz-place-container","children":[` +
+		`{"title":"One","type":"text/x-moz-place","uri":"https://example.com/1?utm_source=x&id=1"},` +
+		`{"title":"One again","type":"text/x-moz-place","uri":"https://example.com/1?id=1"},` +
+		`{"title":"Recent","type":"text/x-moz-place","uri":"place:sort=8&maxResults=10"},` +
+		`{"title":"Two","type":"text/x-moz-place","uri":"https:\/\/example.com\/2?fbclid=abc"}]}]}`
+
+	testCases := []struct {
+		name     string
+		merge    bool
+		expected string
+	}{
+		{
+			name: "URLs are cleaned in their original encoding",
+			expected: strings.NewReplacer(
+				`1?utm_source=x&id=1"`, `1?id=1"`,
+				`2?fbclid=abc"`, `2"`,
+			).Replace(input),
+		},
+		{
+			name:  "Duplicates are merged",
+			merge: true,
+			expected: strings.NewReplacer(
+				`1?utm_source=x&id=1"`, `1?id=1"`,
+				`{"title":"One again","type":"text/x-moz-place","uri":"https://example.com/1?id=1"},`, ``,
+				`2?fbclid=abc"`, `2"`,
+			).Replace(input),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, duplicates, err := NewEngine(GenericTracking).RewriteBookmarks(FirefoxBookmarks, []byte(input), tc.merge)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+			if len(duplicates) != 1 || duplicates[0].Bookmark.Title != "One again" {
+				t.Errorf("Unexpected duplicates: %+v", duplicates)
+			}
+		})
+	}
+}
+
+func TestBookmarkListRemove(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		remove   []int
+		expected string
+	}{
+		{name: "First", input: `[1, 2, 3]`, remove: []int{0}, expected: `[2, 3]`},
+		{name: "Middle", input: `[1, 2, 3]`, remove: []int{1}, expected: `[1, 3]`},
+		{name: "Last", input: `[1, 2, 3]`, remove: []int{2}, expected: `[1, 2]`},
+		{name: "Last two", input: "[\n 1,\n 2,\n 3\n]", remove: []int{1, 2}, expected: "[\n 1\n]"},
+		{name: "First two", input: `[1, 2, 3]`, remove: []int{0, 1}, expected: `[3]`},
+		{name: "All", input: `[ 1, 2 ]`, remove: []int{0, 1}, expected: `[  ]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			array, err := parseJSON([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			list := &bookmarkList{separated: true}
+			for _, elem := range array.elems {
+				list.entries = append(list.entries, byteRange{elem.start, elem.stop})
+			}
+			indexes := make(map[int]bool)
+			for _, i := range tc.remove {
+				indexes[i] = true
+			}
+
+			result := string(applyEdits([]byte(tc.input), list.remove(indexes)))
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
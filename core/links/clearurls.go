@@ -0,0 +1,187 @@
+package links
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// clearURLsData is the ClearURLs rules database, data.min.json
+type clearURLsData struct {
+	Providers map[string]clearURLsProviderData `json:"providers"`
+}
+
+// clearURLsProviderData is a provider of the ClearURLs rules database. Its
+// patterns are JavaScript regular expressions, matched case insensitively.
+type clearURLsProviderData struct {
+	URLPattern        string   `json:"urlPattern"`
+	CompleteProvider  bool     `json:"completeProvider"`
+	Rules             []string `json:"rules"`
+	RawRules          []string `json:"rawRules"`
+	ReferralMarketing []string `json:"referralMarketing"`
+	Exceptions        []string `json:"exceptions"`
+	Redirections      []string `json:"redirections"`
+	ForceRedirection  bool     `json:"forceRedirection"`
+}
+
+// clearURLsProvider is a ClearURLs provider compiled into a Transform
+type clearURLsProvider struct {
+	name         string
+	urlPattern   *regexp.Regexp
+	exceptions   []*regexp.Regexp
+	redirections []*regexp.Regexp
+	rawRules     []*regexp.Regexp
+	rules        []*regexp.Regexp // matched against whole field names
+}
+
+// LoadClearURLs reads a local copy of the ClearURLs rules database and
+// compiles it with ParseClearURLs.
+func LoadClearURLs(path string) ([]Transform, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ClearURLs rules: %w", err)
+	}
+	return ParseClearURLs(data)
+}
+
+// ParseClearURLs compiles the ClearURLs rules database, data.min.json, into
+// one Transform per provider, named clearurls- followed by the provider's
+// name and sorted by it. A provider applies to the URLs its urlPattern
+// matches and none of its exceptions do. A matching redirection replaces the
+// URL with the one it captures; otherwise rawRules are removed from the whole
+// URL and query and fragment fields named by rules or referralMarketing are
+// removed. Complete providers, which block URLs outright, have nothing to
+// clean and are left out.
+//
+// The patterns are JavaScript regular expressions. Those Go cannot compile,
+// such as lookarounds, are skipped and described in the returned list, along
+// with the providers whose urlPattern is one of them.
+func ParseClearURLs(data []byte) ([]Transform, []string, error) {
+	var db clearURLsData
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, nil, fmt.Errorf("invalid ClearURLs rules: %w", err)
+	}
+	if db.Providers == nil {
+		return nil, nil, fmt.Errorf("invalid ClearURLs rules: no providers")
+	}
+
+	var skipped []string
+	compile := func(provider, field, pattern string) *regexp.Regexp {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %s %q: %v", provider, field, pattern, err))
+		}
+		return re
+	}
+	compileAll := func(provider, field string, patterns []string, format string) []*regexp.Regexp {
+		var compiled []*regexp.Regexp
+		for _, pattern := range patterns {
+			if re := compile(provider, field, fmt.Sprintf(format, pattern)); re != nil {
+				compiled = append(compiled, re)
+			}
+		}
+		return compiled
+	}
+
+	names := make([]string, 0, len(db.Providers))
+	for name := range db.Providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var transforms []Transform
+	for _, name := range names {
+		p := db.Providers[name]
+		if p.CompleteProvider {
+			continue
+		}
+		urlPattern := compile(name, "urlPattern", p.URLPattern)
+		if urlPattern == nil {
+			continue
+		}
+		transforms = append(transforms, &clearURLsProvider{
+			name:         "clearurls-" + transformName(name),
+			urlPattern:   urlPattern,
+			exceptions:   compileAll(name, "exception", p.Exceptions, "%s"),
+			redirections: compileAll(name, "redirection", p.Redirections, "%s"),
+			rawRules:     compileAll(name, "rawRule", p.RawRules, "%s"),
+			rules:        compileAll(name, "rule", append(slices.Clone(p.Rules), p.ReferralMarketing...), "^(?:%s)$"),
+		})
+	}
+	return transforms, skipped, nil
+}
+
+func (p *clearURLsProvider) Name() string {
+	return p.name
+}
+
+func (p *clearURLsProvider) Description() string {
+	return "ClearURLs " + strings.TrimPrefix(p.name, "clearurls-") + " rules"
+}
+
+// MatchHost accepts every host, since providers match whole URLs
+func (p *clearURLsProvider) MatchHost(string) bool {
+	return true
+}
+
+func (p *clearURLsProvider) Apply(u *url.URL) bool {
+	before := u.String()
+	if !p.urlPattern.MatchString(before) {
+		return false
+	}
+	for _, exception := range p.exceptions {
+		if exception.MatchString(before) {
+			return false
+		}
+	}
+
+	for _, redirection := range p.redirections {
+		match := redirection.FindStringSubmatch(before)
+		if len(match) < 2 || match[1] == "" {
+			continue
+		}
+		target, err := url.PathUnescape(match[1])
+		if err != nil {
+			continue
+		}
+		if parsed, err := url.Parse(target); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+			*u = *parsed
+			return true
+		}
+	}
+
+	cleaned := before
+	for _, rawRule := range p.rawRules {
+		cleaned = rawRule.ReplaceAllString(cleaned, "")
+	}
+	if cleaned != before {
+		parsed, err := url.Parse(cleaned)
+		if err != nil {
+			return false
+		}
+		*u = *parsed
+	}
+
+	isRule := func(field string) bool {
+		return slices.ContainsFunc(p.rules, func(re *regexp.Regexp) bool { return re.MatchString(field) })
+	}
+	removeQueryParams(u, isRule)
+	if fields, err := parseFragmentParams(u.Fragment); err == nil && fields != nil {
+		changed := false
+		for field := range fields {
+			if isRule(field) {
+				fields.Del(field)
+				changed = true
+			}
+		}
+		if changed {
+			u.Fragment = buildFragmentFromParams(fields)
+		}
+	}
+
+	return u.String() != before
+}
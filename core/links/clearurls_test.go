@@ -0,0 +1,118 @@
+package links
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadClearURLs(t *testing.T) {
+	transforms, skipped, err := LoadClearURLs("testdata/clearurls.min.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, transform := range transforms {
+		names = append(names, transform.Name())
+	}
+	// adtech is a complete provider, which blocks URLs rather than cleaning them
+	expectedNames := []string{"clearurls-amazon", "clearurls-amazon-search", "clearurls-bing", "clearurls-globalrules", "clearurls-google"}
+	if diff := cmp.Diff(expectedNames, names); diff != "" {
+		t.Errorf("Transform names mismatch (-want +got):\n%s", diff)
+	}
+
+	// bing's lookahead is not valid in Go
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0], "bing: rule ") {
+		t.Errorf("Skipped = %q, want bing's lookahead rule", skipped)
+	}
+
+	engine := NewEngine(transforms...)
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Global rules",
+			input:    "https://example.com/a?id=1&utm_source=x&fbclid=abc",
+			expected: "https://example.com/a?id=1",
+		},
+		{
+			name:     "Global rules in the fragment",
+			input:    "https://example.com/a#section=2&utm_medium=email",
+			expected: "https://example.com/a#section=2",
+		},
+		{
+			name:     "Referral marketing",
+			input:    "https://example.com/a?ref=newsletter&id=1",
+			expected: "https://example.com/a?id=1",
+		},
+		{
+			name:     "Global exception",
+			input:    "https://api.github.com/repos/a/b?utm_source=x",
+			expected: "https://api.github.com/repos/a/b?utm_source=x",
+		},
+		{
+			name:     "Rules and raw rules",
+			input:    "https://www.amazon.de/dp/B0TEST/ref=sr_1_1?crid=2X&keywords=kettle&qid=1&tag=aff-21&th=1",
+			expected: "https://www.amazon.de/dp/B0TEST",
+		},
+		{
+			name:     "Provider exception leaves others to apply",
+			input:    "https://www.amazon.com/s?k=kettle&crid=2X&qid=1&rh=n%3A1",
+			expected: "https://www.amazon.com/s?k=kettle&rh=n%3A1",
+		},
+		{
+			name:     "Redirection",
+			input:    "https://www.google.com/url?sa=t&rct=j&url=https%3A%2F%2Fexample.com%2Fpost%3Fid%3D1%26utm_source%3Dgoogle&usg=AOv",
+			expected: "https://example.com/post?id=1",
+		},
+		{
+			name:     "Google rules",
+			input:    "https://www.google.com/search?q=kettle&ei=abc&ved=2ah&sourceid=chrome",
+			expected: "https://www.google.com/search?q=kettle",
+		},
+		{
+			name:     "Google exception",
+			input:    "https://mail.google.com/mail/u/0/?ei=abc#inbox",
+			expected: "https://mail.google.com/mail/u/0/?ei=abc#inbox",
+		},
+		{
+			name:     "Rules are matched against whole names",
+			input:    "https://www.bing.com/search?q=kettle&form=QBLH&formal=1",
+			expected: "https://www.bing.com/search?formal=1&q=kettle",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.CleanURL(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("CleanURL() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseClearURLsErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "Invalid JSON", input: `{"providers":`},
+		{name: "No providers", input: `{"rules":[]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := ParseClearURLs([]byte(tc.input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
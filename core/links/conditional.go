@@ -11,31 +11,35 @@ type ConditionalParamGroup struct {
 	Params []string // Parameters that must all be present to be removed
 }
 
+// These parameters must all be present to be removed
+var conditionalGroups = []ConditionalParamGroup{
+	{
+		Params: []string{"isFreemail", "r", "triedRedirect"},
+	},
+	// Add more conditional groups here as needed
+	// {
+	//     Params: []string{"param1", "param2", "param3"},
+	// },
+}
+
+// Conditional removes parameter groups only when every parameter in the group is present
+var Conditional = NewTransform("conditional", "conditional parameter removal", anyHost, cleanConditionalParams)
+
 // RemoveConditionalParams removes parameters only when all parameters in a group are present
 func RemoveConditionalParams(r io.Reader, w io.Writer) error {
-	// These parameters must all be present to be removed
-	conditionalGroups := []ConditionalParamGroup{
-		{
-			Params: []string{"isFreemail", "r", "triedRedirect"},
-		},
-		// Add more conditional groups here as needed
-		// {
-		//     Params: []string{"param1", "param2", "param3"},
-		// },
-	}
+	return AsFunc(Conditional)(r, w)
+}
 
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		for _, group := range conditionalGroups {
-			if shouldRemoveParams(u, group.Params) {
-				q := u.Query()
-				for _, param := range group.Params {
-					q.Del(param)
-				}
-				u.RawQuery = q.Encode()
+func cleanConditionalParams(u *url.URL) {
+	for _, group := range conditionalGroups {
+		if shouldRemoveParams(u, group.Params) {
+			q := u.Query()
+			for _, param := range group.Params {
+				q.Del(param)
 			}
+			u.RawQuery = q.Encode()
 		}
-		return u
-	})
+	}
 }
 
 // shouldRemoveParams checks if all parameters in the group are present in the URL
@@ -0,0 +1,328 @@
+package links
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxPasses bounds how often the transforms run over a single URL before the
+// engine gives up on reaching a stable result.
+const maxPasses = 10
+
+// Change records a URL rewritten by an Engine.
+type Change struct {
+	Site       URLSite
+	Cleaned    string
+	Transforms []string
+}
+
+// Engine rewrites every URL in a document in a single pass. The document is
+// scanned once, each URL is parsed once and only the transforms whose host
+// matcher accepts the URL's host run on it. Matches are cached per host, so
+// an Engine is cheapest when reused across documents. It is safe for
+// concurrent use.
+type Engine struct {
+	transforms []Transform
+
+	// SyncLinkText makes Rewrite also update the text of inline links that
+	// display their destination, e.g. [example.com/a?utm_source=x](...), so
+	// that it shows the cleaned destination in the same style.
+	SyncLinkText bool
+
+	mu     sync.RWMutex
+	byHost map[string][]int
+}
+
+// NewEngine returns an Engine that applies transforms in order.
+func NewEngine(transforms ...Transform) *Engine {
+	return &Engine{
+		transforms: transforms,
+		byHost:     make(map[string][]int),
+	}
+}
+
+// Transforms returns the transforms the engine applies, in order.
+func (e *Engine) Transforms() []Transform {
+	return e.transforms
+}
+
+// OscillationError reports transforms that keep undoing each other's rewrite
+// of the same URL, so that the URL never reaches a stable form.
+type OscillationError struct {
+	URL        string
+	Transforms []string
+}
+
+func (e *OscillationError) Error() string {
+	return fmt.Sprintf("transforms %s keep undoing each other's rewrite of %s",
+		strings.Join(e.Transforms, " and "), e.URL)
+}
+
+// Rewrite returns content with every URL cleaned, along with the URLs that
+// changed. Content without changes is returned as is.
+func (e *Engine) Rewrite(content []byte) ([]byte, []Change, error) {
+	sites, linkText := findURLSites(content)
+	return e.rewrite(content, sites, linkText)
+}
+
+// URLFinder returns the URLs in a document of one format. Finders of formats
+// that must be parsed fail on content that does not parse.
+type URLFinder func(content []byte) ([]URLSite, error)
+
+// The URLFinders of the formats RewriteURLs handles. Markdown has Rewrite,
+// which also syncs link text.
+var (
+	HTMLURLs     = scanner(FindHTMLURLs)
+	TextURLs     = scanner(FindTextURLs)
+	OrgURLs      = scanner(FindOrgURLs)
+	RSTURLs      = scanner(FindRSTURLs)
+	AsciiDocURLs = scanner(FindAsciiDocURLs)
+	JSONURLs     = URLFinder(FindJSONURLs)
+	YAMLURLs     = URLFinder(FindYAMLURLs)
+	TOMLURLs     = URLFinder(FindTOMLURLs)
+	FeedURLs     = URLFinder(FindFeedURLs)
+)
+
+// scanner makes a URLFinder of a function that finds URLs in any content
+func scanner(find func([]byte) []URLSite) URLFinder {
+	return func(content []byte) ([]URLSite, error) {
+		return find(content), nil
+	}
+}
+
+// RewriteURLs returns content with the URLs find finds in it cleaned, along
+// with the URLs that changed. Content without changes is returned as is; an
+// error from find is returned with nothing rewritten.
+func (e *Engine) RewriteURLs(content []byte, find URLFinder) ([]byte, []Change, error) {
+	sites, err := find(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.rewrite(content, sites, nil)
+}
+
+func (e *Engine) rewrite(content []byte, sites []URLSite, linkText map[int]byteRange) ([]byte, []Change, error) {
+	var changes []Change
+	var edits []edit
+
+	for _, site := range sites {
+		cleaned, names, err := e.cleanSite(site)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d, column %d: %w", site.Line, site.Column, err)
+		}
+		if cleaned == site.URL {
+			continue
+		}
+		changes = append(changes, Change{Site: site, Cleaned: cleaned, Transforms: names})
+		edits = append(edits, edit{site.Offset, site.Offset + len(site.URL), cleaned})
+
+		text, ok := linkText[site.Offset]
+		if !e.SyncLinkText || !ok {
+			continue
+		}
+		style, ok := displayedURL(string(content[text.start:text.stop]), site.URL)
+		if !ok {
+			continue
+		}
+		// the synced text replaces any URL cleaned within it
+		within := func(start int) bool {
+			return start >= text.start && start < text.stop
+		}
+		edits = slices.DeleteFunc(edits, func(ed edit) bool { return within(ed.start) })
+		changes = slices.DeleteFunc(changes, func(c Change) bool { return within(c.Site.Offset) })
+		edits = append(edits, edit{text.start, text.stop, style.display(cleaned)})
+	}
+
+	if changes == nil {
+		return content, nil, nil
+	}
+	return applyEdits(content, edits), changes, nil
+}
+
+// cleanSite cleans the URL of site, decoding and re-encoding the character
+// references of HTML attributes and the escapes of quoted strings
+func (e *Engine) cleanSite(site URLSite) (string, []string, error) {
+	decoded := decodedURL(site)
+	cleaned, names, err := e.CleanURL(decoded)
+	if err != nil || cleaned == decoded {
+		return site.URL, nil, err
+	}
+	return encodedURL(site, cleaned), names, nil
+}
+
+// decodedURL returns the URL of site with the escapes of its syntax decoded
+func decodedURL(site URLSite) string {
+	switch site.Syntax {
+	case SyntaxHTMLAttribute, SyntaxMarkupText:
+		return html.UnescapeString(site.URL)
+	case SyntaxEscapedHTML:
+		return html.UnescapeString(html.UnescapeString(site.URL))
+	case SyntaxEscapedString:
+		return decodeJSONString(site.URL)
+	case SyntaxSingleQuotedString:
+		return decodeSingleQuoted(site.URL)
+	default:
+		return site.URL
+	}
+}
+
+// encodedURL returns cleaned encoded for the syntax of site, in the style of
+// the URL it replaces
+func encodedURL(site URLSite, cleaned string) string {
+	switch site.Syntax {
+	case SyntaxHTMLAttribute, SyntaxMarkupText:
+		return encodeHTMLAttribute(cleaned, site.URL)
+	case SyntaxEscapedHTML:
+		inner := encodeHTMLAttribute(cleaned, html.UnescapeString(site.URL))
+		return encodeHTMLAttribute(inner, site.URL)
+	case SyntaxEscapedString:
+		return encodeJSONString(cleaned, site.URL)
+	case SyntaxSingleQuotedString:
+		return strings.ReplaceAll(cleaned, "'", "''")
+	default:
+		return cleaned
+	}
+}
+
+// TraceStep records a URL before and after a transform rewrote it.
+type TraceStep struct {
+	Transform string
+	Before    string
+	After     string
+}
+
+// Changed reports whether the step rewrote the URL.
+func (s TraceStep) Changed() bool {
+	return s.Before != s.After
+}
+
+// CleanURL runs rawURL through the matching transforms until none of them
+// changes it any more, and returns the result with the names of the
+// transforms that changed it. URLs that fail to parse are returned unchanged.
+// Transforms that keep undoing each other are reported as an
+// *OscillationError.
+func (e *Engine) CleanURL(rawURL string) (string, []string, error) {
+	return e.clean(rawURL, nil)
+}
+
+// TraceURL cleans rawURL as CleanURL does and returns the result with every
+// rewrite made on the way, in order. Re-encoding left over once the
+// transforms are done is reported as a url-normalization step.
+func (e *Engine) TraceURL(rawURL string) (string, []TraceStep, error) {
+	var steps []TraceStep
+	cleaned, _, err := e.clean(rawURL, func(step TraceStep) {
+		steps = append(steps, step)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	last := rawURL
+	if len(steps) > 0 {
+		last = steps[len(steps)-1].After
+	}
+	if cleaned != last {
+		steps = append(steps, TraceStep{Transform: "url-normalization", Before: last, After: cleaned})
+	}
+	return cleaned, steps, nil
+}
+
+// clean implements CleanURL, passing every rewrite to trace if it is set
+func (e *Engine) clean(rawURL string, trace func(TraceStep)) (string, []string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, nil, nil
+	}
+
+	// seen maps every form the URL has taken to the number of rewrites
+	// applied when it was first reached
+	var applied []string
+	previous := u.String()
+	seen := map[string]int{previous: 0}
+
+	for pass := 0; ; pass++ {
+		if pass == maxPasses {
+			return "", nil, fmt.Errorf("%s did not stabilize after %d passes", rawURL, maxPasses)
+		}
+
+		changed := false
+		host := hostOf(u)
+		matching := e.matching(host)
+		for i := 0; i < len(matching); i++ {
+			transform := e.transforms[matching[i]]
+			// the host was matched when the transforms were picked
+			if !applyMatched(transform, u) {
+				continue
+			}
+			changed = true
+			applied = append(applied, transform.Name())
+
+			current := u.String()
+			if trace != nil {
+				trace(TraceStep{Transform: transform.Name(), Before: previous, After: current})
+			}
+			previous = current
+			if first, ok := seen[current]; ok {
+				return "", nil, &OscillationError{URL: rawURL, Transforms: unique(applied[first:])}
+			}
+			seen[current] = len(applied)
+
+			// a rewritten host, e.g. youtube.com to youtu.be, changes which of
+			// the remaining transforms apply
+			if newHost := hostOf(u); newHost != host {
+				host = newHost
+				next := matching[i]
+				matching = e.matching(host)
+				i = -1
+				for i+1 < len(matching) && matching[i+1] <= next {
+					i++
+				}
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	u.RawQuery = strings.ReplaceAll(u.RawQuery, "%20", "+")
+	return u.String(), unique(applied), nil
+}
+
+// matching returns the indexes of the transforms that match host, in order
+func (e *Engine) matching(host string) []int {
+	e.mu.RLock()
+	indexes, ok := e.byHost[host]
+	e.mu.RUnlock()
+	if ok {
+		return indexes
+	}
+
+	indexes = []int{}
+	for i, transform := range e.transforms {
+		if transform.MatchHost(host) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	e.mu.Lock()
+	e.byHost[host] = indexes
+	e.mu.Unlock()
+	return indexes
+}
+
+// unique returns names without repeats, in order of first appearance
+func unique(names []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,239 @@
+package links
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var engineTestDocument = `# Links
+
+Search: https://www.google.com/search?q=test&hl=en&ei=abc
+Video: https://www.youtube.com/watch?v=dQw4w9WgXcQ&si=abc&utm_source=x
+Short: https://www.youtube.com/shorts/abc123?feature=share
+Post: https://www.reddit.com/r/golang/comments/1?share_id=x&utm_medium=y
+Store: https://www.amazon.com/dp/B000/ref=sr_1_1?tag=x&k=shoes
+Article: https://example.substack.com/p/title?utm_source=twitter
+Fragment: https://example.com/page#:~:text=hello
+Plain: https://example.com/search?q=hello%20world
+` + "```" + `
+https://www.google.com/search?q=code&hl=en
+` + "```" + `
+Trailing: [link](https://www.instagram.com/p/abc?igsh=1)
+`
+
+// chain applies transforms one after another the way core.ApplyTransforms does
+func chain(t testing.TB, content []byte, transforms []Transform) []byte {
+	t.Helper()
+	for _, transform := range transforms {
+		var out bytes.Buffer
+		if err := AsFunc(transform)(bytes.NewReader(content), &out); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content = out.Bytes()
+	}
+	return content
+}
+
+func TestEngineMatchesChain(t *testing.T) {
+	transforms := Builtin()
+	expected := chain(t, []byte(engineTestDocument), transforms)
+
+	result, _, err := NewEngine(transforms...).Rewrite([]byte(engineTestDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(string(expected), string(result)); diff != "" {
+		t.Errorf("Engine output differs from chained transforms (-chain +engine):\n%s", diff)
+	}
+}
+
+func TestEngineRewrite(t *testing.T) {
+	engine := NewEngine(GenericTracking, YouTube, Reddit)
+
+	content := "a https://www.youtube.com/watch?v=abc&utm_source=x b\n" +
+		"c https://example.com/ok d\n" +
+		"e https://old.reddit.com/r/go f"
+
+	result, changes, err := engine.Rewrite([]byte(content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedContent := "a https://youtu.be/abc b\n" +
+		"c https://example.com/ok d\n" +
+		"e https://old.reddit.com/r/go/ f"
+	if diff := cmp.Diff(expectedContent, string(result)); diff != "" {
+		t.Errorf("Unexpected content (-want +got):\n%s", diff)
+	}
+
+	expectedChanges := []Change{
+		{
+			Site:       URLSite{URL: "https://www.youtube.com/watch?v=abc&utm_source=x", Offset: 2, Line: 1, Column: 3},
+			Cleaned:    "https://youtu.be/abc",
+			Transforms: []string{"generic-tracking", "youtube"},
+		},
+		{
+			Site:       URLSite{URL: "https://old.reddit.com/r/go", Offset: 82, Line: 3, Column: 3},
+			Cleaned:    "https://old.reddit.com/r/go/",
+			Transforms: []string{"reddit"},
+		},
+	}
+	if diff := cmp.Diff(expectedChanges, changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestEngineRewriteUnchanged(t *testing.T) {
+	content := []byte("nothing to see at https://example.com/a\n")
+	result, changes, err := NewEngine(Builtin()...).Rewrite(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(content, result) || changes != nil {
+		t.Errorf("Expected content to be returned unchanged, got %q with %v", result, changes)
+	}
+}
+
+func TestEngineCleanURLRematchesAfterHostRewrite(t *testing.T) {
+	rewriteHost := NewTransform("rewrite", "host rewrite", func(host string) bool {
+		return host == "old.example"
+	}, func(u *url.URL) {
+		u.Host = "new.example"
+	})
+	stripQuery := NewTransform("strip", "query removal", func(host string) bool {
+		return host == "new.example"
+	}, func(u *url.URL) {
+		u.RawQuery = ""
+	})
+
+	cleaned, names, err := NewEngine(rewriteHost, stripQuery).CleanURL("https://old.example/a?x=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cleaned != "https://new.example/a" {
+		t.Errorf("CleanURL() = %q, want %q", cleaned, "https://new.example/a")
+	}
+	if diff := cmp.Diff([]string{"rewrite", "strip"}, names); diff != "" {
+		t.Errorf("Unexpected transforms (-want +got):\n%s", diff)
+	}
+}
+
+func TestEngineCleanURLReachesFixpoint(t *testing.T) {
+	// stripWWW only applies once the host has been lowercased by lowerHost,
+	// which runs after it, so a second pass is needed
+	stripWWW := NewTransform("strip-www", "www removal", func(host string) bool {
+		return strings.HasPrefix(host, "www.")
+	}, func(u *url.URL) {
+		u.Host = strings.TrimPrefix(u.Host, "www.")
+	})
+	lowerHost := NewTransform("lower-host", "host lowercasing", anyHost, func(u *url.URL) {
+		u.Host = strings.ToLower(u.Host)
+	})
+
+	cleaned, names, err := NewEngine(stripWWW, lowerHost).CleanURL("https://WWW.Example.com/a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cleaned != "https://example.com/a" {
+		t.Errorf("CleanURL() = %q, want %q", cleaned, "https://example.com/a")
+	}
+	if diff := cmp.Diff([]string{"lower-host", "strip-www"}, names); diff != "" {
+		t.Errorf("Unexpected transforms (-want +got):\n%s", diff)
+	}
+}
+
+func TestEngineCleanURLOscillation(t *testing.T) {
+	addSlash := NewTransform("add-slash", "trailing slash addition", anyHost, func(u *url.URL) {
+		u.Path = addTrailingSlash(u.Path)
+	})
+	stripSlash := NewTransform("strip-slash", "trailing slash removal", anyHost, func(u *url.URL) {
+		u.Path = stripTrailingSlash(u.Path)
+	})
+
+	_, _, err := NewEngine(GenericTracking, addSlash, stripSlash).CleanURL("https://example.com/a?utm_source=x")
+
+	var oscillation *OscillationError
+	if !errors.As(err, &oscillation) {
+		t.Fatalf("Expected an OscillationError, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"add-slash", "strip-slash"}, oscillation.Transforms); diff != "" {
+		t.Errorf("Unexpected transforms (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(err.Error(), "add-slash and strip-slash") {
+		t.Errorf("Error %q does not name both transforms", err)
+	}
+}
+
+func TestEngineRewriteIsIdempotent(t *testing.T) {
+	engine := NewEngine(Builtin()...)
+
+	once, _, err := engine.Rewrite([]byte(engineTestDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	twice, changes, err := engine.Rewrite(once)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(string(once), string(twice)); diff != "" {
+		t.Errorf("Second rewrite changed the document (-first +second):\n%s", diff)
+	}
+	if changes != nil {
+		t.Errorf("Second rewrite reported changes: %v", changes)
+	}
+}
+
+// benchmarkDocument builds a large Markdown document with a mix of clean and
+// dirty URLs across many hosts, plus code blocks.
+func benchmarkDocument() []byte {
+	var sb strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&sb, "## Section %d\n\n", i)
+		sb.WriteString("Some prose without links that goes on for a while to make lines realistic.\n")
+		fmt.Fprintf(&sb, "See https://example.com/articles/%d?utm_source=newsletter for details.\n", i)
+		fmt.Fprintf(&sb, "Video https://www.youtube.com/watch?v=vid%d&si=abc and search https://www.google.com/search?q=%d&hl=en\n", i, i)
+		fmt.Fprintf(&sb, "- [Docs](https://docs.example.org/page/%d)\n", i)
+		sb.WriteString("```\nhttps://www.google.com/search?q=code&hl=en\n```\n\n")
+	}
+	return []byte(sb.String())
+}
+
+func BenchmarkChain(b *testing.B) {
+	content := benchmarkDocument()
+	transforms := Builtin()
+	funcs := make([]func(io.Reader, io.Writer) error, 0, len(transforms))
+	for _, transform := range transforms {
+		funcs = append(funcs, AsFunc(transform))
+	}
+
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		current := content
+		for _, f := range funcs {
+			var out bytes.Buffer
+			if err := f(bytes.NewReader(current), &out); err != nil {
+				b.Fatal(err)
+			}
+			current = out.Bytes()
+		}
+	}
+}
+
+func BenchmarkEngine(b *testing.B) {
+	content := benchmarkDocument()
+	engine := NewEngine(Builtin()...)
+
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Rewrite(content)
+	}
+}
@@ -0,0 +1,272 @@
+package links
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// feedURLAttributes are the attributes of RSS, Atom and OPML elements whose
+// value is a URL, by lowercased local name: Atom's <link href>, RSS's
+// <enclosure url> and <source url>, OPML's xmlUrl, htmlUrl and url, and the
+// usual HTML attributes of inline XHTML content
+var feedURLAttributes = map[string]bool{
+	"href":    true,
+	"htmlurl": true,
+	"src":     true,
+	"uri":     true,
+	"url":     true,
+	"xmlurl":  true,
+}
+
+// feedURLElements are the elements whose text is a URL, by lowercased local
+// name. Identifiers such as <guid> and Atom's <id> are left alone, since
+// readers use them to recognize entries they have already seen.
+var feedURLElements = map[string]bool{
+	"comments": true,
+	"docs":     true,
+	"icon":     true,
+	"link":     true,
+	"logo":     true,
+	"uri":      true,
+	"url":      true,
+}
+
+// feedHTMLElements are the elements whose text is HTML whatever their type
+// attribute says: RSS's <description> and <content:encoded>
+var feedHTMLElements = map[string]bool{
+	"description": true,
+	"encoded":     true,
+}
+
+// atomTextElements are the Atom text constructs, whose text is HTML when
+// their type attribute is html
+var atomTextElements = map[string]bool{
+	"content":  true,
+	"rights":   true,
+	"subtitle": true,
+	"summary":  true,
+	"title":    true,
+}
+
+// xmlTokenKind is the kind of an xmlToken
+type xmlTokenKind int
+
+const (
+	xmlStartTag xmlTokenKind = iota
+	xmlEndTag
+	xmlEmptyTag
+	xmlText
+	xmlCDATA // start and stop exclude <![CDATA[ and ]]>
+)
+
+// xmlToken is a token of an XML document, as byte offsets into it
+type xmlToken struct {
+	kind        xmlTokenKind
+	start, stop int
+	name        string // lowercased local name of tags
+}
+
+// validateXML reports whether content is a well-formed XML document. HTML
+// entities such as &nbsp; are accepted, as feeds in the wild use them.
+func validateXML(content []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(content))
+	d.Entity = xml.HTMLEntity
+	for {
+		if _, err := d.Token(); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid XML: %w", err)
+		}
+	}
+}
+
+// scanXML splits a well-formed XML document into tokens, leaving out
+// comments, processing instructions and declarations
+func scanXML(content []byte) []xmlToken {
+	var tokens []xmlToken
+	for i := 0; i < len(content); {
+		if content[i] != '<' {
+			stop := bytes.IndexByte(content[i:], '<')
+			if stop < 0 {
+				stop = len(content) - i
+			}
+			tokens = append(tokens, xmlToken{kind: xmlText, start: i, stop: i + stop})
+			i += stop
+			continue
+		}
+
+		rest := content[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			stop := bytes.Index(rest, []byte("]]>"))
+			tokens = append(tokens, xmlToken{kind: xmlCDATA, start: i + len("<![CDATA["), stop: i + stop})
+			i += stop + len("]]>")
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			i += bytes.Index(rest, []byte("-->")) + len("-->")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			i += bytes.Index(rest, []byte("?>")) + len("?>")
+		case bytes.HasPrefix(rest, []byte("<!")):
+			// a declaration such as <!DOCTYPE>, which may hold a bracketed
+			// internal subset
+			depth := 0
+			j := 2
+			for ; j < len(rest) && (rest[j] != '>' || depth > 0); j++ {
+				switch rest[j] {
+				case '[':
+					depth++
+				case ']':
+					depth--
+				}
+			}
+			i += j + 1
+		default:
+			stop := tagEnd(rest)
+			token := xmlToken{kind: xmlStartTag, start: i, stop: i + stop}
+			nameStart := 1
+			if rest[1] == '/' {
+				token.kind = xmlEndTag
+				nameStart = 2
+			} else if rest[stop-2] == '/' {
+				token.kind = xmlEmptyTag
+			}
+			nameStop := nameStart
+			for nameStop < stop && !isHTMLSpace(rest[nameStop]) && rest[nameStop] != '/' && rest[nameStop] != '>' {
+				nameStop++
+			}
+			token.name = localName(string(rest[nameStart:nameStop]))
+			tokens = append(tokens, token)
+			i += stop
+		}
+	}
+	return tokens
+}
+
+// tagEnd returns the length of the tag tag starts with, up to and including
+// its closing >, skipping over quoted attribute values
+func tagEnd(tag []byte) int {
+	var quote byte
+	for i := 1; i < len(tag); i++ {
+		switch {
+		case quote != 0:
+			if tag[i] == quote {
+				quote = 0
+			}
+		case tag[i] == '"' || tag[i] == '\'':
+			quote = tag[i]
+		case tag[i] == '>':
+			return i + 1
+		}
+	}
+	return len(tag)
+}
+
+// localName returns a qualified name without its prefix, lowercased
+func localName(name string) string {
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(name)
+}
+
+// FindFeedURLs returns the URLs of an RSS, Atom or OPML document, in document
+// order: the URL attributes of its elements, the text of link elements and
+// the URLs in the HTML of descriptions and content, whether that HTML is in
+// CDATA sections or escaped. Entry identifiers are left alone. Content that is
+// not well-formed XML is an error.
+func FindFeedURLs(content []byte) ([]URLSite, error) {
+	if err := validateXML(content); err != nil {
+		return nil, err
+	}
+
+	m := strictURLMatcher()
+	var sites []URLSite
+	// the kind of text each open element holds
+	type element struct {
+		url, html bool
+	}
+	var open []element
+	for _, token := range scanXML(content) {
+		switch token.kind {
+		case xmlStartTag, xmlEmptyTag:
+			var e element
+			e.url = feedURLElements[token.name]
+			e.html = feedHTMLElements[token.name]
+			for _, attr := range tagAttributes(content[token.start:token.stop]) {
+				start, stop := token.start+attr.valueStart, token.start+attr.valueStop
+				value := html.UnescapeString(string(content[start:stop]))
+				name := localName(attr.name)
+				if name == "type" && atomTextElements[token.name] {
+					e.html = value == "html"
+				}
+				if feedURLAttributes[name] && m.isURL(strings.TrimSpace(value)) {
+					start, stop = trimHTMLSpace(content, start, stop)
+					sites = append(sites, URLSite{URL: string(content[start:stop]), Offset: start, Syntax: SyntaxHTMLAttribute})
+				}
+			}
+			if token.kind == xmlStartTag {
+				open = append(open, e)
+			}
+		case xmlEndTag:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xmlText, xmlCDATA:
+			if len(open) == 0 {
+				continue
+			}
+			e := open[len(open)-1]
+			switch {
+			case e.url:
+				sites = append(sites, feedLinkSites(content, token, m)...)
+			case e.html:
+				sites = append(sites, feedHTMLSites(content, token)...)
+			}
+		}
+	}
+
+	setLineColumns(content, sites)
+	return sites, nil
+}
+
+// feedLinkSites returns the site of the text of a link element, if it is a
+// URL
+func feedLinkSites(content []byte, token xmlToken, m *urlMatcher) []URLSite {
+	start, stop := trimHTMLSpace(content, token.start, token.stop)
+	raw := string(content[start:stop])
+	site := URLSite{URL: raw, Offset: start, Syntax: SyntaxMarkupText}
+	if token.kind == xmlCDATA {
+		site.Syntax = SyntaxBare
+	}
+	if !m.isURL(decodedURL(site)) {
+		return nil
+	}
+	return []URLSite{site}
+}
+
+// feedHTMLSites returns the URLs of the HTML held by a text or CDATA token.
+// Escaped HTML is unescaped to find them, and their sites cover the escaped
+// text they came from.
+func feedHTMLSites(content []byte, token xmlToken) []URLSite {
+	raw := content[token.start:token.stop]
+	if token.kind == xmlCDATA {
+		sites := findHTMLContentSites(raw)
+		for i := range sites {
+			sites[i].Offset += token.start
+		}
+		return sites
+	}
+
+	decoded, offsets := unescapeHTML(raw)
+	sites := findHTMLContentSites(decoded)
+	for i, site := range sites {
+		start := token.start + offsets[site.Offset]
+		stop := token.start + offsets[site.Offset+len(site.URL)]
+		sites[i] = URLSite{URL: string(content[start:stop]), Offset: start, Syntax: SyntaxEscapedHTML}
+	}
+	return sites
+}
@@ -0,0 +1,90 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteFeed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "RSS links, enclosures and guids",
+			input: "<?xml version=\"1.0\"?>\n<rss version=\"2.0\"><channel>\n" +
+				"<link>https://example.com/?utm_source=rss</link>\n<item>\n" +
+				"<link>\n  https://example.com/a?id=1&amp;utm_source=rss\n</link>\n" +
+				"<comments><![CDATA[https://example.com/a?utm_source=rss#comments]]></comments>\n" +
+				"<guid>https://example.com/a?utm_source=rss</guid>\n" +
+				"<enclosure url=\"https://example.com/a.mp3?utm_source=rss\" type=\"audio/mpeg\"/>\n" +
+				"</item></channel></rss>\n",
+			expected: "<?xml version=\"1.0\"?>\n<rss version=\"2.0\"><channel>\n" +
+				"<link>https://example.com/</link>\n<item>\n" +
+				"<link>\n  https://example.com/a?id=1\n</link>\n" +
+				"<comments><![CDATA[https://example.com/a#comments]]></comments>\n" +
+				"<guid>https://example.com/a?utm_source=rss</guid>\n" +
+				"<enclosure url=\"https://example.com/a.mp3\" type=\"audio/mpeg\"/>\n" +
+				"</item></channel></rss>\n",
+		},
+		{
+			name: "RSS description in CDATA",
+			input: "<rss><channel><item><description><![CDATA[<p>Read <a href=\"https://example.com/b?id=1&amp;utm_source=rss\">this</a> " +
+				"or https://example.com/c?utm_source=rss</p>]]></description></item></channel></rss>",
+			expected: "<rss><channel><item><description><![CDATA[<p>Read <a href=\"https://example.com/b?id=1\">this</a> " +
+				"or https://example.com/c</p>]]></description></item></channel></rss>",
+		},
+		{
+			name: "RSS content in escaped HTML",
+			input: "<rss xmlns:content=\"http://purl.org/rss/1.0/modules/content/\"><channel><item>" +
+				"<content:encoded>&lt;a href=&quot;https://example.com/b?id=1&amp;amp;utm_source=rss&quot;&gt;é&lt;/a&gt; " +
+				"https://example.com/c?id=1&amp;amp;utm_source=rss</content:encoded></item></channel></rss>",
+			expected: "<rss xmlns:content=\"http://purl.org/rss/1.0/modules/content/\"><channel><item>" +
+				"<content:encoded>&lt;a href=&quot;https://example.com/b?id=1&quot;&gt;é&lt;/a&gt; " +
+				"https://example.com/c?id=1</content:encoded></item></channel></rss>",
+		},
+		{
+			name: "Atom links, ids and HTML content",
+			input: "<feed xmlns=\"http://www.w3.org/2005/Atom\">\n" +
+				"<link rel=\"alternate\" href=\"https://example.com/?utm_source=atom\"/>\n" +
+				"<entry><id>https://example.com/a?utm_source=atom</id>\n" +
+				"<summary type=\"text\">https://example.com/s?utm_source=atom</summary>\n" +
+				"<content type=\"html\">&lt;img src=\"https://example.com/i.png?utm_source=atom\"&gt;</content>\n" +
+				"</entry></feed>\n",
+			expected: "<feed xmlns=\"http://www.w3.org/2005/Atom\">\n" +
+				"<link rel=\"alternate\" href=\"https://example.com/\"/>\n" +
+				"<entry><id>https://example.com/a?utm_source=atom</id>\n" +
+				"<summary type=\"text\">https://example.com/s?utm_source=atom</summary>\n" +
+				"<content type=\"html\">&lt;img src=\"https://example.com/i.png\"&gt;</content>\n" +
+				"</entry></feed>\n",
+		},
+		{
+			name: "OPML outlines",
+			input: "<opml version=\"2.0\"><body>\n" +
+				"<outline text=\"News\" xmlUrl=\"https://example.com/feed?utm_source=opml\" htmlUrl=\"https://example.com/?utm_source=opml&amp;id=2\"/>\n" +
+				"</body></opml>\n",
+			expected: "<opml version=\"2.0\"><body>\n" +
+				"<outline text=\"News\" xmlUrl=\"https://example.com/feed\" htmlUrl=\"https://example.com/?id=2\"/>\n" +
+				"</body></opml>\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), FeedURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := engine.RewriteURLs([]byte("<rss><channel></rss>"), FeedURLs); err == nil {
+		t.Error("Expected an error for XML that is not well-formed")
+	}
+}
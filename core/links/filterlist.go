@@ -0,0 +1,334 @@
+package links
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// filterListIgnoredOptions are the filter options that restrict which
+// requests a browser filters. Links in documents are not requests of any
+// particular type, so these options do not narrow which URLs are cleaned.
+var filterListIgnoredOptions = []string{
+	"1p", "3p", "all", "doc", "document", "first-party", "important", "other",
+	"script", "stylesheet", "subdocument", "third-party", "xhr", "xmlhttprequest",
+}
+
+// removeParamFilter is a $removeparam filter of a uBlock Origin or AdGuard
+// filter list
+type removeParamFilter struct {
+	pattern  *regexp.Regexp // the URLs the filter applies to, nil for all
+	domains  []string       // the hosts the filter applies to, with their subdomains, empty for all
+	excluded []string       // the hosts the filter does not apply to, with their subdomains
+	value    string         // the value of the $removeparam option, which exceptions refer to
+	param    string         // the name of the parameter to remove, empty for all
+	regex    *regexp.Regexp // matched against name=value instead of param
+	negate   bool           // remove the parameters that do not match instead
+}
+
+// filterList is a filter list compiled into a Transform
+type filterList struct {
+	name       string
+	filters    []removeParamFilter
+	exceptions []removeParamFilter // @@ filters, whose value names the filters they lift
+}
+
+// LoadFilterList reads a filter list and compiles it with ParseFilterList,
+// naming it filterlist- followed by the file's name without its extension.
+func LoadFilterList(path string) (Transform, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read filter list: %w", err)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := transformName(base)
+	if name == "" {
+		return nil, nil, fmt.Errorf("filter list %q: no name can be derived from the file name", path)
+	}
+	return ParseFilterList("filterlist-"+name, data)
+}
+
+// ParseFilterList compiles the $removeparam filters of a uBlock Origin or
+// AdGuard filter list into a Transform named name. A filter removes the
+// query parameter it names; with a ~ the parameters it does not name; with a
+// /regex/ the parameters whose name=value matches; and with no value the
+// whole query. It applies to the URLs its pattern matches, such as
+// ||example.com^, and to the hosts listed by its domain option, which here
+// are the hosts of the URLs being cleaned rather than of the pages linking
+// to them.
+//
+// Exception filters, starting with @@, lift the $removeparam filters with the
+// same value from the URLs they match; one without a value lifts them all.
+// Other filters, comments and cosmetic filters are ignored. $removeparam
+// filters that use options with no meaning outside the browser are skipped
+// and described in the returned list.
+func ParseFilterList(name string, data []byte) (Transform, []string, error) {
+	list := &filterList{name: name}
+	var skipped []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		exception := strings.HasPrefix(line, "@@")
+		filter, ok, err := parseRemoveParamFilter(strings.TrimPrefix(line, "@@"))
+		switch {
+		case err != nil:
+			skipped = append(skipped, fmt.Sprintf("%s:%d: %q: %v", name, lineNumber, line, err))
+		case ok && exception:
+			list.exceptions = append(list.exceptions, filter)
+		case ok:
+			list.filters = append(list.filters, filter)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read filter list %s: %w", name, err)
+	}
+	return list, skipped, nil
+}
+
+// parseRemoveParamFilter parses a line of a filter list. It returns false
+// for lines that are not $removeparam filters.
+func parseRemoveParamFilter(line string) (removeParamFilter, bool, error) {
+	var filter removeParamFilter
+	if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") || isCosmeticFilter(line) {
+		return filter, false, nil
+	}
+	i := strings.IndexByte(line, '$')
+	if i < 0 {
+		return filter, false, nil
+	}
+	pattern, options := line[:i], splitFilterOptions(line[i+1:])
+
+	removeParam := slices.IndexFunc(options, func(option string) bool {
+		name, _, _ := strings.Cut(option, "=")
+		return name == "removeparam" || name == "queryprune"
+	})
+	if removeParam < 0 {
+		return filter, false, nil
+	}
+
+	matchCase := false
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		switch {
+		case name == "removeparam" || name == "queryprune":
+			if err := filter.parseValue(value); err != nil {
+				return filter, false, err
+			}
+		case name == "domain":
+			for _, domain := range strings.Split(value, "|") {
+				domain = strings.ToLower(domain)
+				if excluded, ok := strings.CutPrefix(domain, "~"); ok {
+					filter.excluded = append(filter.excluded, excluded)
+				} else if domain != "" {
+					filter.domains = append(filter.domains, domain)
+				}
+			}
+		case name == "match-case":
+			matchCase = true
+		case slices.Contains(filterListIgnoredOptions, strings.TrimPrefix(name, "~")):
+		default:
+			return filter, false, fmt.Errorf("unsupported option %q", name)
+		}
+	}
+
+	if pattern != "" && pattern != "*" {
+		expr := filterPatternRegex(pattern)
+		if !matchCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return filter, false, fmt.Errorf("invalid pattern: %w", err)
+		}
+		filter.pattern = re
+	}
+	return filter, true, nil
+}
+
+// parseValue parses the value of a $removeparam option
+func (f *removeParamFilter) parseValue(value string) error {
+	f.value = value
+	value, f.negate = strings.CutPrefix(value, "~")
+	if !strings.HasPrefix(value, "/") {
+		f.param = value
+		return nil
+	}
+	end := strings.LastIndexByte(value, '/')
+	if end == 0 {
+		return fmt.Errorf("unterminated regular expression %q", value)
+	}
+	expr, flags := value[1:end], value[end+1:]
+	switch flags {
+	case "":
+	case "i":
+		expr = "(?i)" + expr
+	default:
+		return fmt.Errorf("unsupported regular expression flags %q", flags)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	f.regex = re
+	return nil
+}
+
+// isCosmeticFilter reports whether a line hides or alters page elements
+// rather than filtering requests
+func isCosmeticFilter(line string) bool {
+	for _, separator := range []string{"##", "#@#", "#?#", "#$#", "#%#"} {
+		if strings.Contains(line, separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFilterOptions splits filter options at commas that are not escaped,
+// as they may be within regular expressions
+func splitFilterOptions(options string) []string {
+	var result []string
+	var current strings.Builder
+	for i := 0; i < len(options); i++ {
+		switch {
+		case options[i] == '\\' && i+1 < len(options) && options[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case options[i] == ',':
+			result = append(result, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(options[i])
+		}
+	}
+	return append(result, current.String())
+}
+
+// filterPatternRegex translates the URL pattern of a filter into a regular
+// expression. || anchors at the start of a host, | at the start or end of
+// the URL, * matches anything and ^ a separator or the end of the URL.
+func filterPatternRegex(pattern string) string {
+	var b strings.Builder
+	switch {
+	case strings.HasPrefix(pattern, "||"):
+		b.WriteString(`^[a-z][a-z0-9+.-]*://(?:[^/?#]*\.)?`)
+		pattern = pattern[2:]
+	case strings.HasPrefix(pattern, "|"):
+		b.WriteString("^")
+		pattern = pattern[1:]
+	}
+	anchorEnd := strings.HasSuffix(pattern, "|")
+	pattern = strings.TrimSuffix(pattern, "|")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '^':
+			b.WriteString(`(?:[^\w.%-]|$)`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if anchorEnd {
+		b.WriteString("$")
+	}
+	return b.String()
+}
+
+// matchURL reports whether the filter applies to u, whose string form is raw
+func (f removeParamFilter) matchURL(u *url.URL, raw string) bool {
+	host := hostOf(u)
+	inDomain := func(domain string) bool {
+		return HostMatcher{Hosts: []string{domain}, SubdomainsOf: []string{domain}}.Match(host)
+	}
+	if slices.ContainsFunc(f.excluded, inDomain) {
+		return false
+	}
+	if len(f.domains) > 0 && !slices.ContainsFunc(f.domains, inDomain) {
+		return false
+	}
+	return f.pattern == nil || f.pattern.MatchString(raw)
+}
+
+// removes reports whether the filter removes the query parameter name with
+// the value value
+func (f removeParamFilter) removes(name, value string) bool {
+	var match bool
+	switch {
+	case f.regex != nil:
+		match = f.regex.MatchString(name + "=" + value)
+	case f.param != "":
+		match = name == f.param
+	default:
+		return true
+	}
+	return match != f.negate
+}
+
+func (l *filterList) Name() string {
+	return l.name
+}
+
+func (l *filterList) Description() string {
+	return l.name + " $removeparam filters"
+}
+
+// MatchHost accepts every host, since filters match whole URLs
+func (l *filterList) MatchHost(string) bool {
+	return true
+}
+
+func (l *filterList) Apply(u *url.URL) bool {
+	if u.RawQuery == "" {
+		return false
+	}
+	raw := u.String()
+	var lifted []string
+	for _, exception := range l.exceptions {
+		if exception.matchURL(u, raw) {
+			if exception.value == "" {
+				return false
+			}
+			lifted = append(lifted, exception.value)
+		}
+	}
+	var filters []removeParamFilter
+	for _, filter := range l.filters {
+		if filter.matchURL(u, raw) && !slices.Contains(lifted, filter.value) {
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return false
+	}
+
+	q := u.Query()
+	changed := false
+	for name, values := range q {
+		kept := slices.DeleteFunc(slices.Clone(values), func(value string) bool {
+			return slices.ContainsFunc(filters, func(filter removeParamFilter) bool {
+				return filter.removes(name, value)
+			})
+		})
+		if len(kept) == len(values) {
+			continue
+		}
+		changed = true
+		if len(kept) == 0 {
+			q.Del(name)
+		} else {
+			q[name] = kept
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return changed
+}
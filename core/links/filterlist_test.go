@@ -0,0 +1,134 @@
+package links
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadFilterList(t *testing.T) {
+	transform, skipped, err := LoadFilterList("testdata/removeparam.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transform.Name() != "filterlist-removeparam" {
+		t.Errorf("Name() = %q, want %q", transform.Name(), "filterlist-removeparam")
+	}
+
+	expectedSkipped := []string{
+		`filterlist-removeparam:23: "$removeparam=fbclid,redirect=noopjs": unsupported option "redirect"`,
+	}
+	if diff := cmp.Diff(expectedSkipped, skipped); diff != "" {
+		t.Errorf("Skipped mismatch (-want +got):\n%s", diff)
+	}
+
+	engine := NewEngine(transform)
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Plain names",
+			input:    "https://blog.example.net/post?id=1&utm_source=feed&ref_src=twsrc",
+			expected: "https://blog.example.net/post?id=1",
+		},
+		{
+			name:     "Regular expressions match name=value",
+			input:    "https://blog.example.net/post?id=1&utm_medium=email&utm_campaign=x&MC_EID=abc",
+			expected: "https://blog.example.net/post?id=1",
+		},
+		{
+			name:     "Names are matched exactly",
+			input:    "https://blog.example.net/post?utm_source_id=1",
+			expected: "https://blog.example.net/post?utm_source_id=1",
+		},
+		{
+			name:     "Host anchor",
+			input:    "https://www.example.com/a?session=abc&id=1",
+			expected: "https://www.example.com/a?id=1",
+		},
+		{
+			name:     "Exception lifts the filter with the same value",
+			input:    "https://www.example.com/keep/a?session=abc&utm_source=x",
+			expected: "https://www.example.com/keep/a?session=abc",
+		},
+		{
+			name:     "Exception without a value lifts every filter",
+			input:    "https://www.example.com/raw?session=abc&utm_source=x",
+			expected: "https://www.example.com/raw?session=abc&utm_source=x",
+		},
+		{
+			name:     "Host anchor does not match lookalike hosts",
+			input:    "https://notexample.com/a?session=abc&id=1",
+			expected: "https://notexample.com/a?session=abc&id=1",
+		},
+		{
+			name:     "Negation keeps only the named parameter",
+			input:    "https://example.com/search?q=kettle&sxsrf=abc&session=1&ei=2",
+			expected: "https://example.com/search?q=kettle",
+		},
+		{
+			name:     "No value removes the whole query",
+			input:    "https://docs.example.org/guide?from=nav&lang=en",
+			expected: "https://docs.example.org/guide",
+		},
+		{
+			name:     "Domain option",
+			input:    "https://youtu.be/dQw4w9WgXcQ?si=abc&t=42",
+			expected: "https://youtu.be/dQw4w9WgXcQ?t=42",
+		},
+		{
+			name:     "Excluded domain",
+			input:    "https://music.youtube.com/watch?v=1&si=abc",
+			expected: "https://music.youtube.com/watch?v=1&si=abc",
+		},
+		{
+			name:     "Domain option first with an escaped regular expression",
+			input:    "https://x.com/user/status/1?s=20&t=abc",
+			expected: "https://x.com/user/status/1?t=abc",
+		},
+		{
+			name:     "Other domains are left alone",
+			input:    "https://example.net/watch?si=abc",
+			expected: "https://example.net/watch?si=abc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.CleanURL(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("CleanURL() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFilterListSkipped(t *testing.T) {
+	testCases := []struct {
+		name   string
+		filter string
+		reason string
+	}{
+		{name: "Invalid regular expression", filter: `$removeparam=/(?<=a)b/`, reason: "invalid regular expression"},
+		{name: "Unterminated regular expression", filter: `$removeparam=/utm_`, reason: "unterminated regular expression"},
+		{name: "Unsupported flags", filter: `$removeparam=/utm_/g`, reason: "unsupported regular expression flags"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, skipped, err := ParseFilterList("test", []byte(tc.filter))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(skipped) != 1 || !strings.Contains(skipped[0], tc.reason) {
+				t.Errorf("Skipped = %q, want a filter skipped for %q", skipped, tc.reason)
+			}
+		})
+	}
+}
@@ -2,20 +2,10 @@ package links
 
 import (
 	"io"
-	"net/url"
-	"slices"
-	"strings"
 )
 
-var excludePatterns = []string{
-	"google.com/maps/",
-}
-
-var KeepParams = []string{
-	"q",
-	"tbm",
-}
-
+// ParamsToRemove are the Google parameters removed outside search and
+// redirect URLs, whose parameters are restricted to those Google keeps
 var ParamsToRemove = []string{
 	"aep",
 	"bih",
@@ -56,53 +46,24 @@ var ParamsToRemove = []string{
 	"ved",
 }
 
-func RemoveParamsFromGoogleURLs(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		if isExcludedURL(u.String()) {
-			return u
-		}
-		if !strings.Contains(strings.ToLower(u.Hostname()), "google.com") {
-			return u
-		}
-		cleaned, _, err := cleanGoogleURL(u.String())
-		if err != nil {
-			return u
-		}
-		parsed, err := url.Parse(cleaned)
-		if err != nil {
-			return u
-		}
-		return parsed
-	})
-}
+// Google keeps only the meaningful parameters of Google search and redirect
+// URLs, so that new tracking parameters do not slip through, removes
+// ParamsToRemove from other Google URLs and leaves Maps alone
+var Google = mustSiteTransform(SiteRule{
+	Name:                "google",
+	Description:         "Google URL parameter removal",
+	HostMatcher:         HostMatcher{Domains: []string{"google.*"}},
+	ExcludePathPrefixes: []string{"/maps"},
+	Params:              ParamsToRemove,
+	NormalizeQuery:      true,
+	KeepParams: []KeepParams{
+		// the query, the result type, old and new, the time range and the page
+		{PathPrefixes: []string{"/search"}, Params: []string{"q", "start", "tbm", "tbs", "udm"}},
+		// the target of redirects
+		{PathPrefixes: []string{"/url"}, Params: []string{"q", "url"}},
+	},
+})
 
-func isExcludedURL(urlStr string) bool {
-	for _, pattern := range excludePatterns {
-		if strings.Contains(strings.ToLower(urlStr), pattern) {
-			return true
-		}
-	}
-	return false
-}
-
-func cleanGoogleURL(urlStr string) (string, []string, error) {
-	u, err := url.Parse(urlStr)
-	if err != nil {
-		return "", nil, err
-	}
-
-	q := u.Query()
-
-	var remainingParams []string
-
-	for param := range q {
-		if slices.Contains(KeepParams, param) || !slices.Contains(ParamsToRemove, param) {
-			remainingParams = append(remainingParams, param)
-			continue
-		}
-		q.Del(param)
-	}
-
-	u.RawQuery = q.Encode()
-	return u.String(), remainingParams, nil
+func RemoveParamsFromGoogleURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Google)(r, w)
 }
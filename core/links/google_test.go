@@ -24,6 +24,31 @@ func TestRemoveParamsFromGoogleLinks(t *testing.T) {
 			input:    "https://www.google.com/search?udm=2&q=skiing",
 			expected: "https://www.google.com/search?q=skiing&udm=2",
 		},
+		{
+			name:     "Unknown search parameters are removed",
+			input:    "https://www.google.com/search?q=test&sstk=AbC&newtrk=1&tbm=nws",
+			expected: "https://www.google.com/search?q=test&tbm=nws",
+		},
+		{
+			name:     "Google redirect keeps only its target",
+			input:    "https://www.google.com/url?q=https://example.com/&sa=D&source=editors&ust=1700000000&usg=AOvVaw",
+			expected: "https://www.google.com/url?q=https%3A%2F%2Fexample.com%2F",
+		},
+		{
+			name:     "Other Google pages lose tracking parameters",
+			input:    "https://www.google.com/imgres?imgurl=https://example.com/a.jpg&imgrefurl=https://example.com/&ved=0ah&sa=X&ei=abc",
+			expected: "https://www.google.com/imgres?imgrefurl=https%3A%2F%2Fexample.com%2F&imgurl=https%3A%2F%2Fexample.com%2Fa.jpg",
+		},
+		{
+			name:     "Google Books keeps its own parameters",
+			input:    "https://books.google.com/books?id=abc&ved=xyz&sa=X",
+			expected: "https://books.google.com/books?id=abc",
+		},
+		{
+			name:     "Google Maps is left alone",
+			input:    "https://www.google.com/maps/search/coffee?hl=en&entry=ttu",
+			expected: "https://www.google.com/maps/search/coffee?hl=en&entry=ttu",
+		},
 		{
 			name:     "Google link without parameters",
 			input:    "https://www.google.com",
@@ -0,0 +1,84 @@
+package links
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// HostMatcher matches hosts using the Public Suffix List snapshot embedded in
+// golang.org/x/net/publicsuffix, so that lookalikes such as wsj.com.evil.io,
+// evilshopify.com or amazon.evil do not pass for the sites they imitate.
+type HostMatcher struct {
+	// Domains are registrable domains, matched along with their subdomains.
+	// name.* stands for name under any ICANN public suffix, such as
+	// amazon.com, amazon.co.uk and amazon.de.
+	Domains []string `yaml:"domains"`
+	// Hosts are matched exactly.
+	Hosts []string `yaml:"hosts"`
+	// SubdomainsOf are hosts whose subdomains match, at any depth, but not
+	// the hosts themselves.
+	SubdomainsOf []string `yaml:"subdomains_of"`
+}
+
+// empty reports whether the matcher lists no hosts at all
+func (m HostMatcher) empty() bool {
+	return len(m.Domains)+len(m.Hosts)+len(m.SubdomainsOf) == 0
+}
+
+func (m HostMatcher) validate() error {
+	for _, domain := range m.Domains {
+		domain = strings.ToLower(domain)
+		if name, ok := strings.CutSuffix(domain, ".*"); ok {
+			if name == "" || strings.ContainsAny(name, ".*") {
+				return fmt.Errorf("domain %q: only a single label may come before .*", domain)
+			}
+			continue
+		}
+		if registrable, err := publicsuffix.EffectiveTLDPlusOne(domain); err != nil || registrable != domain {
+			return fmt.Errorf("domain %q is not a registrable domain, list it under hosts or subdomains_of", domain)
+		}
+	}
+	for _, host := range append(append([]string{}, m.Hosts...), m.SubdomainsOf...) {
+		if host == "" || strings.ContainsAny(host, "*/:") {
+			return fmt.Errorf("host %q: not a host name", host)
+		}
+	}
+	return nil
+}
+
+// Match reports whether host, a host name without port, matches
+func (m HostMatcher) Match(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, exact := range m.Hosts {
+		if host == strings.ToLower(exact) {
+			return true
+		}
+	}
+	for _, parent := range m.SubdomainsOf {
+		if strings.HasSuffix(host, "."+strings.ToLower(parent)) {
+			return true
+		}
+	}
+	if len(m.Domains) == 0 {
+		return false
+	}
+
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return false
+	}
+	for _, domain := range m.Domains {
+		domain = strings.ToLower(domain)
+		if name, ok := strings.CutSuffix(domain, ".*"); ok {
+			suffix, icann := publicsuffix.PublicSuffix(host)
+			if icann && registrable == name+"."+suffix {
+				return true
+			}
+		} else if registrable == domain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHostMatcher(t *testing.T) {
+	matcher := HostMatcher{
+		Domains:      []string{"wsj.com", "amazon.*"},
+		Hosts:        []string{"mail.example.com"},
+		SubdomainsOf: []string{"myshopify.com"},
+	}
+
+	testCases := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "wsj.com", expected: true},
+		{host: "www.wsj.com", expected: true},
+		{host: "WWW.WSJ.COM.", expected: true},
+		{host: "notwsj.com", expected: false},
+		{host: "wsj.com.evil.io", expected: false},
+		{host: "notwsj.com.evil.io", expected: false},
+		{host: "amazon.com", expected: true},
+		{host: "smile.amazon.com", expected: true},
+		{host: "www.amazon.co.uk", expected: true},
+		{host: "www.amazon.de", expected: true},
+		{host: "amazon.evil", expected: false},
+		{host: "amazon.com.evil.io", expected: false},
+		{host: "myamazon.com", expected: false},
+		{host: "amazon.blogspot.com", expected: false},
+		{host: "mail.example.com", expected: true},
+		{host: "www.mail.example.com", expected: false},
+		{host: "example.com", expected: false},
+		{host: "store.myshopify.com", expected: true},
+		{host: "a.b.myshopify.com", expected: true},
+		{host: "myshopify.com", expected: false},
+		{host: "evilmyshopify.com", expected: false},
+		{host: "localhost", expected: false},
+		{host: "127.0.0.1", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			if got := matcher.Match(tc.host); got != tc.expected {
+				t.Errorf("Match(%q) = %v, want %v", tc.host, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLookalikeHosts(t *testing.T) {
+	// each URL carries a parameter the transform removes on the real site
+	testCases := []struct {
+		transform Transform
+		input     string
+	}{
+		{transform: WSJ, input: "https://notwsj.com.evil.io/articles/x?mod=hp_lead_pos1"},
+		{transform: WSJ, input: "https://wsj.com.evil.io/articles/x?mod=hp_lead_pos1"},
+		{transform: Facebook, input: "https://facebook.com.evil.io/story?tracking=1"},
+		{transform: Facebook, input: "https://notfacebook.com/story?tracking=1"},
+		{transform: LinkedIn, input: "https://linkedin.com.evil.io/posts/x?rcm=1"},
+		{transform: TechCrunch, input: "https://techcrunch.com.evil.io/x?ecid=1"},
+		{transform: Bloomberg, input: "https://bloomberg.com.evil.io/news/x?leadSource=1"},
+		{transform: Shopify, input: "https://evilshopify.com/products/x?pr_rec_id=1"},
+		{transform: Shopify, input: "https://evilmyshopify.com/products/x?pr_rec_id=1"},
+		{transform: TheSweekly, input: "https://notthesweekly.com/p/x?post_id=1"},
+		{transform: Amazon, input: "https://amazon.evil/dp/B08N5WRWNW?tag=x"},
+		{transform: Amazon, input: "https://amazon.com.evil.io/dp/B08N5WRWNW?tag=x"},
+		{transform: Google, input: "https://google.com.evil.io/search?q=x&ei=1"},
+		{transform: Google, input: "https://notgoogle.com/search?q=x&ei=1"},
+		{transform: YouTube, input: "https://youtube.com.evil.io/watch?v=x&si=1"},
+		{transform: YouTube, input: "https://notyoutu.be/x?si=1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.transform.Name()+" "+tc.input, func(t *testing.T) {
+			u, err := url.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.transform.MatchHost(hostOf(u)) {
+				t.Errorf("MatchHost(%q) = true, want false", hostOf(u))
+			}
+			if tc.transform.Apply(u) || u.String() != tc.input {
+				t.Errorf("Apply() changed the URL to %q", u.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package links
+
+import (
+	"bytes"
+	"html"
+	"slices"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// urlAttributes are the HTML attributes whose value is a single URL
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"codebase":   true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"poster":     true,
+	"src":        true,
+	"usemap":     true,
+	"xlink:href": true,
+}
+
+// srcsetAttributes hold comma separated image candidates, each a URL
+// optionally followed by a descriptor
+var srcsetAttributes = map[string]bool{
+	"imagesrcset": true,
+	"srcset":      true,
+}
+
+// spaceSeparatedURLAttributes hold whitespace separated URLs
+var spaceSeparatedURLAttributes = map[string]bool{
+	"archive": true,
+	"ping":    true,
+}
+
+// htmlAttribute is an attribute value in a tag, as byte offsets into the
+// document. The value excludes any quotes.
+type htmlAttribute struct {
+	name                  string
+	valueStart, valueStop int
+}
+
+// htmlLiteralElements are the elements whose text is code or preformatted
+// and is not cleaned
+var htmlLiteralElements = map[string]bool{
+	"code":   true,
+	"pre":    true,
+	"script": true,
+	"style":  true,
+}
+
+// FindHTMLURLs returns the URLs in the attributes of the tags of an HTML
+// document and in its text, in document order. The URL of each site is the
+// raw text, so it may hold character references such as &amp;. Comments and
+// the content of script, style, pre and code elements are skipped.
+func FindHTMLURLs(content []byte) []URLSite {
+	sites := findHTMLContentSites(content)
+	setLineColumns(content, sites)
+	return sites
+}
+
+// findHTMLContentSites returns the URLs of an HTML fragment, without line and
+// column: those in URL attributes and those in its text, which holds
+// character references just like attributes do. The text of script, style,
+// pre and code elements is skipped.
+func findHTMLContentSites(content []byte) []URLSite {
+	sites := findHTMLSites(content, 0, len(content))
+
+	z := nethtml.NewTokenizer(bytes.NewReader(content))
+	offset := 0
+	literal := 0 // the number of open literal elements
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		switch tt {
+		case nethtml.StartTagToken:
+			if name, _ := z.TagName(); htmlLiteralElements[string(name)] {
+				literal++
+			}
+		case nethtml.EndTagToken:
+			if name, _ := z.TagName(); htmlLiteralElements[string(name)] && literal > 0 {
+				literal--
+			}
+		case nethtml.TextToken:
+			if literal > 0 {
+				break
+			}
+			// URLs are found in the decoded text, so that references such
+			// as &gt; end them, and their sites cover the raw text
+			decoded, offsets := unescapeHTML(raw)
+			for _, site := range findBareURLs(decoded, nil) {
+				start := offset + offsets[site.Offset]
+				stop := offset + offsets[site.Offset+len(site.URL)]
+				sites = append(sites, URLSite{URL: string(content[start:stop]), Offset: start, Syntax: SyntaxMarkupText})
+			}
+		}
+		offset += len(raw)
+	}
+
+	slices.SortFunc(sites, func(a, b URLSite) int { return a.Offset - b.Offset })
+	return sites
+}
+
+// unescapeHTML returns text with its character references decoded, along with
+// the offset in text of every byte of the result and of its end
+func unescapeHTML(text []byte) ([]byte, []int) {
+	decoded := make([]byte, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	for i := 0; i < len(text); {
+		if text[i] == '&' {
+			if end := bytes.IndexByte(text[i:], ';'); end > 1 {
+				reference := string(text[i : i+end+1])
+				if value := html.UnescapeString(reference); value != reference {
+					decoded = append(decoded, value...)
+					for range len(value) {
+						offsets = append(offsets, i)
+					}
+					i += end + 1
+					continue
+				}
+			}
+		}
+		decoded = append(decoded, text[i])
+		offsets = append(offsets, i)
+		i++
+	}
+	return decoded, append(offsets, len(text))
+}
+
+// findHTMLSites returns the URL attribute sites of the tags in
+// content[start:stop], without line and column
+func findHTMLSites(content []byte, start, stop int) []URLSite {
+	m := strictURLMatcher()
+	var sites []URLSite
+	addSite := func(start, stop int) {
+		raw := string(content[start:stop])
+		if locs := m.findAll(html.UnescapeString(raw)); len(locs) == 0 || locs[0][0] != 0 {
+			// relative URLs and anything else that is not a URL
+			return
+		}
+		sites = append(sites, URLSite{URL: raw, Offset: start, Syntax: SyntaxHTMLAttribute})
+	}
+
+	z := nethtml.NewTokenizer(bytes.NewReader(content[start:stop]))
+	offset := start
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		if tt == nethtml.StartTagToken || tt == nethtml.SelfClosingTagToken {
+			for _, attr := range tagAttributes(raw) {
+				valueStart := offset + attr.valueStart
+				valueStop := offset + attr.valueStop
+				switch {
+				case urlAttributes[attr.name]:
+					valueStart, valueStop = trimHTMLSpace(content, valueStart, valueStop)
+					if valueStop > valueStart {
+						addSite(valueStart, valueStop)
+					}
+				case srcsetAttributes[attr.name]:
+					for _, candidate := range srcsetURLs(content, valueStart, valueStop) {
+						addSite(candidate.start, candidate.stop)
+					}
+				case spaceSeparatedURLAttributes[attr.name]:
+					for _, field := range spaceSeparated(content, valueStart, valueStop) {
+						addSite(field.start, field.stop)
+					}
+				}
+			}
+		}
+		offset += len(raw)
+	}
+	return sites
+}
+
+// tagAttributes returns the attributes of a raw start tag, <name attr="v">,
+// with offsets relative to the start of the tag
+func tagAttributes(tag []byte) []htmlAttribute {
+	var attrs []htmlAttribute
+	i := 1
+	for i < len(tag) && !isHTMLSpace(tag[i]) && tag[i] != '/' && tag[i] != '>' {
+		i++
+	}
+
+	for i < len(tag) {
+		for i < len(tag) && (isHTMLSpace(tag[i]) || tag[i] == '/') {
+			i++
+		}
+		if i >= len(tag) || tag[i] == '>' {
+			break
+		}
+
+		nameStart := i
+		for i++; i < len(tag) && !isHTMLSpace(tag[i]) && tag[i] != '=' && tag[i] != '>' && tag[i] != '/'; i++ {
+		}
+		attr := htmlAttribute{name: strings.ToLower(string(tag[nameStart:i]))}
+
+		j := i
+		for j < len(tag) && isHTMLSpace(tag[j]) {
+			j++
+		}
+		if j >= len(tag) || tag[j] != '=' {
+			continue
+		}
+		for j++; j < len(tag) && isHTMLSpace(tag[j]); j++ {
+		}
+		if j >= len(tag) {
+			break
+		}
+
+		if quote := tag[j]; quote == '"' || quote == '\'' {
+			attr.valueStart = j + 1
+			end := bytes.IndexByte(tag[j+1:], quote)
+			if end < 0 {
+				break
+			}
+			attr.valueStop = j + 1 + end
+			i = attr.valueStop + 1
+		} else {
+			attr.valueStart = j
+			for j < len(tag) && !isHTMLSpace(tag[j]) && tag[j] != '>' {
+				j++
+			}
+			attr.valueStop = j
+			i = j
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// srcsetURLs returns the URL of each image candidate in a srcset value
+func srcsetURLs(content []byte, start, stop int) []byteRange {
+	var urls []byteRange
+	i := start
+	for i < stop {
+		for i < stop && (isHTMLSpace(content[i]) || content[i] == ',') {
+			i++
+		}
+		urlStart := i
+		for i < stop && !isHTMLSpace(content[i]) {
+			i++
+		}
+		urlStop := i
+		// a URL directly followed by a comma has no descriptor
+		for urlStop > urlStart && content[urlStop-1] == ',' {
+			urlStop--
+		}
+		if urlStop > urlStart {
+			urls = append(urls, byteRange{urlStart, urlStop})
+		}
+		if urlStop < i {
+			continue
+		}
+		// skip the descriptor
+		for i < stop && content[i] != ',' {
+			i++
+		}
+	}
+	return urls
+}
+
+// spaceSeparated returns the whitespace separated fields of a value
+func spaceSeparated(content []byte, start, stop int) []byteRange {
+	var fields []byteRange
+	for i := start; i < stop; {
+		for i < stop && isHTMLSpace(content[i]) {
+			i++
+		}
+		fieldStart := i
+		for i < stop && !isHTMLSpace(content[i]) {
+			i++
+		}
+		if i > fieldStart {
+			fields = append(fields, byteRange{fieldStart, i})
+		}
+	}
+	return fields
+}
+
+func trimHTMLSpace(content []byte, start, stop int) (int, int) {
+	for start < stop && isHTMLSpace(content[start]) {
+		start++
+	}
+	for stop > start && isHTMLSpace(content[stop-1]) {
+		stop--
+	}
+	return start, stop
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// encodeHTMLAttribute encodes a cleaned URL for the attribute value it
+// replaces, following the original's use of character references: an
+// ampersand stays bare only if the original had bare ampersands and no
+// references at all.
+func encodeHTMLAttribute(cleaned, original string) string {
+	ampersand := "&amp;"
+	if strings.Contains(original, "&") && html.UnescapeString(original) == original {
+		ampersand = "&"
+	}
+	return strings.NewReplacer(
+		"&", ampersand,
+		`"`, "&quot;",
+		"'", "&#39;",
+		"<", "&lt;",
+		">", "&gt;",
+	).Replace(cleaned)
+}
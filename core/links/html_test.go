@@ -0,0 +1,135 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindHTMLURLs(t *testing.T) {
+	content := `<a href="https://a.example/?x=1&amp;y=2">a</a>
+<img src=https://b.example/i.png srcset="https://c.example/1x.png 1x, https://c.example/2x.png 2x">
+<!-- <a href="https://d.example/">d</a> -->
+<script>var u = "<a href='https://e.example/'>";</script>
+<a href="/relative" ping=" https://f.example/p ">f</a>
+<p>see https://g.example/?a=1&amp;b=2</p><pre>https://h.example/</pre>`
+
+	expected := []URLSite{
+		{URL: "https://a.example/?x=1&amp;y=2", Offset: 9, Line: 1, Column: 10, Syntax: SyntaxHTMLAttribute},
+		{URL: "https://b.example/i.png", Offset: 56, Line: 2, Column: 10, Syntax: SyntaxHTMLAttribute},
+		{URL: "https://c.example/1x.png", Offset: 88, Line: 2, Column: 42, Syntax: SyntaxHTMLAttribute},
+		{URL: "https://c.example/2x.png", Offset: 117, Line: 2, Column: 71, Syntax: SyntaxHTMLAttribute},
+		{URL: "https://f.example/p", Offset: 276, Line: 5, Column: 28, Syntax: SyntaxHTMLAttribute},
+		{URL: "https://g.example/?a=1&amp;b=2", Offset: 311, Line: 6, Column: 8, Syntax: SyntaxMarkupText},
+	}
+
+	result := FindHTMLURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewriteHTMLAttributes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Encoded ampersands stay encoded",
+			input:    `<a href="https://example.com/a?id=1&amp;utm_source=x&amp;b=2">a</a>`,
+			expected: `<a href="https://example.com/a?b=2&amp;id=1">a</a>`,
+		},
+		{
+			name:     "Bare ampersands stay bare",
+			input:    `<a href="https://example.com/a?id=1&utm_source=x&b=2">a</a>`,
+			expected: `<a href="https://example.com/a?b=2&id=1">a</a>`,
+		},
+		{
+			name:     "Numeric character references are decoded",
+			input:    `<a href='https://example.com/a?utm_source&#61;x'>a</a>`,
+			expected: `<a href='https://example.com/a'>a</a>`,
+		},
+		{
+			name:     "Unquoted value",
+			input:    `<img src=https://example.com/i.png?utm_source=x alt="">`,
+			expected: `<img src=https://example.com/i.png alt="">`,
+		},
+		{
+			name:     "Srcset candidates",
+			input:    `<img srcset="https://example.com/1.png?utm_source=x 1x,https://example.com/2.png?utm_source=y 2x">`,
+			expected: `<img srcset="https://example.com/1.png 1x,https://example.com/2.png 2x">`,
+		},
+		{
+			name:     "Unrelated attributes are left alone",
+			input:    `<p title="https://example.com/?utm_source=x">a</p>`,
+			expected: `<p title="https://example.com/?utm_source=x">a</p>`,
+		},
+		{
+			name:     "URLs in text",
+			input:    `<p>See https://example.com/a?id=1&amp;utm_source=x&amp;b=2, or &lt;https://example.com/?utm_source=y&gt;</p>`,
+			expected: `<p>See https://example.com/a?b=2&amp;id=1, or &lt;https://example.com/&gt;</p>`,
+		},
+		{
+			name:     "Code, preformatted text and scripts are left alone",
+			input:    "<pre><code>curl https://example.com/?utm_source=x</code>\nhttps://example.com/?utm_source=y</pre>\n<code>https://example.com/?utm_source=z</code><script>u = 'https://example.com/?utm_source=w'</script>\n",
+			expected: "<pre><code>curl https://example.com/?utm_source=x</code>\nhttps://example.com/?utm_source=y</pre>\n<code>https://example.com/?utm_source=z</code><script>u = 'https://example.com/?utm_source=w'</script>\n",
+		},
+		{
+			name:     "Comments are left alone",
+			input:    `<!-- <a href="https://example.com/?utm_source=x"> -->`,
+			expected: `<!-- <a href="https://example.com/?utm_source=x"> -->`,
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), HTMLURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewriteMarkdownInlineHTML(t *testing.T) {
+	input := "Text <a href=\"https://example.com/a?utm_source=x&amp;id=1\">link</a> and\n" +
+		"\n" +
+		"<div>\n" +
+		"<img src=\"https://example.com/i.png?utm_source=y\">\n" +
+		"</div>\n" +
+		"\n" +
+		"<!-- <a href=\"https://example.com/c?utm_source=z\"> -->\n"
+
+	expected := "Text <a href=\"https://example.com/a?id=1\">link</a> and\n" +
+		"\n" +
+		"<div>\n" +
+		"<img src=\"https://example.com/i.png\">\n" +
+		"</div>\n" +
+		"\n" +
+		"<!-- <a href=\"https://example.com/c?utm_source=z\"> -->\n"
+
+	result, _, err := NewEngine(GenericTracking).Rewrite([]byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, string(result)); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnescapeHTML(t *testing.T) {
+	decoded, offsets := unescapeHTML([]byte("a&amp;b&#233;&unknown;"))
+
+	if diff := cmp.Diff("a&bé&unknown;", string(decoded)); diff != "" {
+		t.Errorf("Unexpected text (-want +got):\n%s", diff)
+	}
+	expected := []int{0, 1, 6, 7, 7, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22}
+	if diff := cmp.Diff(expected, offsets); diff != "" {
+		t.Errorf("Unexpected offsets (-want +got):\n%s", diff)
+	}
+}
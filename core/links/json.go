@@ -0,0 +1,148 @@
+package links
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonValue is a value in a JSON document, as byte offsets into it. Strings
+// hold their decoded value, objects their members in document order and
+// arrays their elements.
+type jsonValue struct {
+	start, stop int
+	kind        byte // '{', '[', '"' or 0 for numbers, booleans and null
+	str         string
+	members     []jsonMember
+	elems       []*jsonValue
+}
+
+// jsonMember is a member of a JSON object
+type jsonMember struct {
+	key   string
+	value *jsonValue
+}
+
+// member returns the value of the object member named key, or nil
+func (v *jsonValue) member(key string) *jsonValue {
+	for _, m := range v.members {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// memberString returns the string value of the object member named key, or
+// "" when there is no such string member
+func (v *jsonValue) memberString(key string) string {
+	if m := v.member(key); m != nil && m.kind == '"' {
+		return m.str
+	}
+	return ""
+}
+
+// parseJSON parses a JSON document, keeping the offsets of every value
+func parseJSON(content []byte) (*jsonValue, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	p := jsonParser{content: content}
+	v, _ := p.value(0)
+	return v, nil
+}
+
+// jsonParser walks a document that is known to be valid JSON
+type jsonParser struct {
+	content []byte
+}
+
+func (p *jsonParser) value(i int) (*jsonValue, int) {
+	i = p.space(i)
+	v := &jsonValue{start: i}
+	switch c := p.content[i]; c {
+	case '{':
+		v.kind = c
+		for i = p.space(i + 1); p.content[i] != '}'; {
+			key, next := p.value(i)
+			value, next := p.value(p.space(next) + 1)
+			v.members = append(v.members, jsonMember{key: key.str, value: value})
+			i = p.separator(next)
+		}
+		i++
+	case '[':
+		v.kind = c
+		for i = p.space(i + 1); p.content[i] != ']'; {
+			elem, next := p.value(i)
+			v.elems = append(v.elems, elem)
+			i = p.separator(next)
+		}
+		i++
+	case '"':
+		v.kind = c
+		j := i + 1
+		for p.content[j] != '"' {
+			if p.content[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		v.str = decodeJSONString(string(p.content[i+1 : j]))
+		i = j + 1
+	default:
+		for i < len(p.content) && !strings.ContainsRune(",]} \t\r\n", rune(p.content[i])) {
+			i++
+		}
+	}
+	v.stop = i
+	return v, i
+}
+
+// separator skips the whitespace and any comma after a member or element
+func (p *jsonParser) separator(i int) int {
+	i = p.space(i)
+	if p.content[i] == ',' {
+		i = p.space(i + 1)
+	}
+	return i
+}
+
+func (p *jsonParser) space(i int) int {
+	for i < len(p.content) && strings.IndexByte(" \t\r\n", p.content[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// decodeJSONString returns the value of the raw text between the quotes of a
+// JSON string
+func decodeJSONString(raw string) string {
+	if !strings.ContainsRune(raw, '\\') {
+		return raw
+	}
+	var s string
+	if err := json.Unmarshal([]byte(`"`+raw+`"`), &s); err != nil {
+		return raw
+	}
+	return s
+}
+
+// encodeJSONString encodes a cleaned URL as the raw text of the JSON string it
+// replaces, escaping / and the HTML special characters where the original
+// did.
+func encodeJSONString(cleaned, original string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(strings.Contains(original, `\u0026`) || strings.Contains(original, `\u003c`) || strings.Contains(original, `\u003e`))
+	if err := enc.Encode(cleaned); err != nil {
+		return original
+	}
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	encoded = encoded[1 : len(encoded)-1]
+	if strings.Contains(original, `\/`) {
+		encoded = strings.ReplaceAll(encoded, "/", `\/`)
+	}
+	return encoded
+}
@@ -0,0 +1,104 @@
+package links
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linkTextStyle records how the text of a link displays its destination, so
+// that the cleaned destination can be shown the same way
+type linkTextStyle struct {
+	prefix, suffix string // surrounding whitespace and backticks
+	dropScheme     bool   // example.com/a rather than https://example.com/a
+	dropSlash      bool   // example.com rather than example.com/
+	ellipsis       string // trailing … or ... of truncated text
+	shown          int    // bytes of the URL shown before the ellipsis
+}
+
+var ellipses = []string{"…", "..."}
+
+// displayedURL reports whether link text displays rawURL, in full or
+// truncated, with or without its scheme, and how it does so
+func displayedURL(text, rawURL string) (linkTextStyle, bool) {
+	var style linkTextStyle
+
+	inner := strings.TrimLeftFunc(text, unicode.IsSpace)
+	style.prefix = text[:len(text)-len(inner)]
+	inner = strings.TrimRightFunc(inner, unicode.IsSpace)
+	style.suffix = text[len(style.prefix)+len(inner):]
+	if len(inner) >= 2 && inner[0] == '`' && inner[len(inner)-1] == '`' {
+		inner = inner[1 : len(inner)-1]
+		style.prefix += "`"
+		style.suffix = "`" + style.suffix
+	}
+
+	for _, ellipsis := range ellipses {
+		if strings.HasSuffix(inner, ellipsis) {
+			style.ellipsis = ellipsis
+			inner = strings.TrimSuffix(inner, ellipsis)
+			break
+		}
+	}
+	if inner == "" {
+		return linkTextStyle{}, false
+	}
+
+	for _, dropScheme := range []bool{false, true} {
+		for _, dropSlash := range []bool{false, true} {
+			style.dropScheme = dropScheme
+			style.dropSlash = dropSlash
+			shown := style.format(rawURL)
+			if style.ellipsis == "" && inner == shown {
+				return style, true
+			}
+			// truncated text must at least show the whole host
+			if style.ellipsis != "" && strings.HasPrefix(shown, inner) && len(inner) >= len(style.format(hostPrefix(rawURL))) {
+				style.shown = len(inner)
+				return style, true
+			}
+		}
+	}
+	return linkTextStyle{}, false
+}
+
+// display returns link text showing rawURL in this style. Truncated text is
+// cut at the same length as before; text that no longer needs truncating is
+// shown in full without the ellipsis.
+func (s linkTextStyle) display(rawURL string) string {
+	shown := s.format(rawURL)
+	if s.ellipsis != "" && len(shown) > s.shown {
+		cut := s.shown
+		for cut > 0 && !utf8.RuneStart(shown[cut]) {
+			cut--
+		}
+		shown = shown[:cut] + s.ellipsis
+	}
+	return s.prefix + shown + s.suffix
+}
+
+// format applies the scheme and trailing slash conventions of the style
+func (s linkTextStyle) format(rawURL string) string {
+	if s.dropScheme {
+		if i := strings.Index(rawURL, "://"); i >= 0 {
+			rawURL = rawURL[i+3:]
+		}
+	}
+	if s.dropSlash && strings.HasSuffix(rawURL, "/") {
+		rawURL = strings.TrimSuffix(rawURL, "/")
+	}
+	return rawURL
+}
+
+// hostPrefix returns rawURL up to the end of its host
+func hostPrefix(rawURL string) string {
+	start := strings.Index(rawURL, "://")
+	if start < 0 {
+		return rawURL
+	}
+	start += 3
+	if stop := strings.IndexAny(rawURL[start:], "/?#"); stop >= 0 {
+		return rawURL[:start+stop]
+	}
+	return rawURL
+}
@@ -0,0 +1,102 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteSyncsLinkText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Text is the full URL",
+			input:    "[https://example.com/a?utm_source=x](https://example.com/a?utm_source=x)",
+			expected: "[https://example.com/a](https://example.com/a)",
+		},
+		{
+			name:     "Text without scheme",
+			input:    "[example.com/a?utm_source=x](https://example.com/a?utm_source=x)",
+			expected: "[example.com/a](https://example.com/a)",
+		},
+		{
+			name:     "Text without scheme for a rewritten host",
+			input:    "[www.youtube.com/watch?v=abc&si=x](https://www.youtube.com/watch?v=abc&si=x)",
+			expected: "[youtu.be/abc](https://youtu.be/abc)",
+		},
+		{
+			name:     "Truncated text that now fits",
+			input:    "[https://example.com/a?utm_so…](https://example.com/a?utm_source=x)",
+			expected: "[https://example.com/a](https://example.com/a)",
+		},
+		{
+			name:     "Truncated text stays truncated",
+			input:    "[example.com/articles/2024/a-long…](https://example.com/articles/2024/a-long-title?utm_source=x)",
+			expected: "[example.com/articles/2024/a-long…](https://example.com/articles/2024/a-long-title)",
+		},
+		{
+			name:     "Truncated text with three dots",
+			input:    "[https://example.com/articles/a-long...](https://example.com/articles/a-long-title?utm_source=x)",
+			expected: "[https://example.com/articles/a-long...](https://example.com/articles/a-long-title)",
+		},
+		{
+			name:     "Text in a code span",
+			input:    "[`example.com/a?utm_source=x`](https://example.com/a?utm_source=x)",
+			expected: "[`example.com/a`](https://example.com/a)",
+		},
+		{
+			name:     "Descriptive text is kept",
+			input:    "[Example article](https://example.com/a?utm_source=x)",
+			expected: "[Example article](https://example.com/a)",
+		},
+		{
+			name:     "Text showing a different URL is kept",
+			input:    "[example.org/a?utm_source=x](https://example.com/a?utm_source=x)",
+			expected: "[example.org/a?utm_source=x](https://example.com/a)",
+		},
+		{
+			name:     "Truncated text must show the whole host",
+			input:    "[exam…](https://example.com/a?utm_source=x)",
+			expected: "[exam…](https://example.com/a)",
+		},
+	}
+
+	engine := NewEngine(GenericTracking, YouTube)
+	engine.SyncLinkText = true
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.Rewrite([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewriteSyncLinkTextReportsDestinationOnly(t *testing.T) {
+	engine := NewEngine(GenericTracking)
+	engine.SyncLinkText = true
+
+	_, changes, err := engine.Rewrite([]byte("[https://example.com/a?utm_source=x](https://example.com/a?utm_source=x)"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Change{
+		{
+			Site:       URLSite{URL: "https://example.com/a?utm_source=x", Offset: 37, Line: 1, Column: 38, Syntax: SyntaxInlineLink},
+			Cleaned:    "https://example.com/a",
+			Transforms: []string{"generic-tracking"},
+		},
+	}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+}
@@ -4,33 +4,112 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
 var (
-	markdownLinkRegex          = regexp.MustCompile(`\[([^][]+)\](\(((?:[^()]+|\([^()]*\))+)\))`)
-	markdownLinkWithTitleRegex = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\s+"[^"]+"\)`)
+	// markdownLinkWithTitleRegex matches the parenthesized part of an inline
+	// link that carries a title, in any of the three forms CommonMark allows:
+	// "title", 'title' and (title). The first group is the destination.
+	markdownLinkWithTitleRegex = regexp.MustCompile(`^\s*(<[^<>\n]*>|[^\s<]\S*)\s+(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\))\s*$`)
 	whitespaceRegex            = regexp.MustCompile(`[\s\t\n\r]+`)
+	autolinkRegex              = regexp.MustCompile(`<[A-Za-z][A-Za-z0-9+.\-]{1,31}:[^<>\x00-\x20]*>`)
 )
 
+// markdownLink is an inline link or image, [text](destination "title"), or a
+// reference link, [text][label], [text][] or [text]. All fields are byte
+// offsets into the document. The text, destination and label ranges exclude
+// the surrounding brackets and parentheses; the URL range is the destination
+// without title and angle brackets.
+type markdownLink struct {
+	start, stop           int
+	textStart, textStop   int
+	destStart, destStop   int
+	urlStart, urlStop     int
+	labelStart, labelStop int
+	image                 bool
+	reference             bool
+}
+
+// markdownDocument is a Markdown document with the structure the link
+// transforms need: what is not prose, where HTML is, the reference
+// definitions and the labels they define, and any front matter.
+type markdownDocument struct {
+	content     []byte
+	skip        []byteRange
+	html        []byteRange
+	definitions []referenceDefinition
+	labels      map[string]bool
+	frontMatter *frontMatter
+}
+
+// edit replaces content[start:stop] with text
+type edit struct {
+	start, stop int
+	text        string
+}
+
+func parseMarkdownDocument(content []byte) *markdownDocument {
+	skip, html := nonProseRanges(content)
+	fm := findFrontMatter(content)
+	if fm != nil {
+		// front matter is data, whatever it looks like as Markdown
+		skip = append([]byteRange{fm.block}, skip...)
+		html = slices.DeleteFunc(html, func(r byteRange) bool { return r.start < fm.block.stop })
+	}
+	doc := &markdownDocument{
+		content:     content,
+		skip:        skip,
+		html:        html,
+		definitions: findReferenceDefinitions(content, skip),
+		labels:      make(map[string]bool),
+		frontMatter: fm,
+	}
+	for _, definition := range doc.definitions {
+		doc.labels[normalizeLabel(content[definition.labelStart:definition.labelStop])] = true
+		doc.skip = append(doc.skip, byteRange{definition.start, definition.stop})
+	}
+	sort.Slice(doc.skip, func(i, j int) bool {
+		return doc.skip[i].start < doc.skip[j].start
+	})
+	return doc
+}
+
 func RemoveWhitespaceFromMarkdownLinks(r io.Reader, w io.Writer) error {
 	buf, err := io.ReadAll(r)
 	if err != nil {
 		return fmt.Errorf("CleanupMarkdownLinks: failed to read input: %w", err)
 	}
 
-	cleaned := markdownLinkRegex.ReplaceAllFunc(buf, func(match []byte) []byte {
-		submatches := markdownLinkRegex.FindSubmatch(match)
-		if len(submatches) >= 4 {
-			description := whitespaceRegex.ReplaceAllString(string(submatches[1]), " ")
-			description = strings.TrimSpace(description)
-			url := string(submatches[3])
-			return []byte(fmt.Sprintf("[%s](%s)", description, url))
+	doc := parseMarkdownDocument(buf)
+	var edits []edit
+	collapse := func(start, stop int) {
+		var description strings.Builder
+		for _, part := range doc.splitCode(start, stop) {
+			if part.code {
+				description.WriteString(part.text)
+			} else {
+				description.WriteString(whitespaceRegex.ReplaceAllString(part.text, " "))
+			}
 		}
-		return match
-	})
+		edits = append(edits, edit{start, stop, strings.TrimSpace(description.String())})
+	}
+	for _, link := range doc.links() {
+		if link.image {
+			continue
+		}
+		collapse(link.textStart, link.textStop)
+		if link.labelStop > link.labelStart {
+			collapse(link.labelStart, link.labelStop)
+		}
+	}
+	for _, definition := range doc.definitions {
+		collapse(definition.labelStart, definition.labelStop)
+	}
 
-	_, err = w.Write(cleaned)
+	_, err = w.Write(applyEdits(buf, edits))
 	if err != nil {
 		return fmt.Errorf("CleanupMarkdownLinks: failed to write output: %w", err)
 	}
@@ -38,20 +117,314 @@ func RemoveWhitespaceFromMarkdownLinks(r io.Reader, w io.Writer) error {
 	return nil
 }
 
+// textPart is a piece of link text, either code and inline HTML, whose
+// whitespace is content, or the prose around it
+type textPart struct {
+	text string
+	code bool
+}
+
+// splitCode splits the text between start and stop into its code spans,
+// backticks included, inline HTML and the prose around them
+func (d *markdownDocument) splitCode(start, stop int) []textPart {
+	content := d.content
+	var parts []textPart
+	last := start
+	for _, r := range d.skip {
+		if r.stop <= last || r.start >= stop {
+			continue
+		}
+		r.start, r.stop = codeSpanBounds(content, max(r.start, last), min(r.stop, stop), last, stop)
+		parts = append(parts, textPart{string(content[last:r.start]), false}, textPart{string(content[r.start:r.stop]), true})
+		last = r.stop
+	}
+	return append(parts, textPart{string(content[last:stop]), false})
+}
+
+// codeSpanBounds widens the content of a code span between start and stop to
+// the backtick strings around it, within lo and hi. The content of a code
+// span excludes the spaces next to its backticks. Ranges without backticks
+// around them, such as inline HTML, are returned as they are.
+func codeSpanBounds(content []byte, start, stop, lo, hi int) (int, int) {
+	from := start
+	for from > lo && isSpaceByte(content[from-1]) {
+		from--
+	}
+	to := stop
+	for to < hi && isSpaceByte(content[to]) {
+		to++
+	}
+	if from == lo || content[from-1] != '`' || to == hi || content[to] != '`' {
+		return start, stop
+	}
+	for from > lo && content[from-1] == '`' {
+		from--
+	}
+	for to < hi && content[to] == '`' {
+		to++
+	}
+	return from, to
+}
+
 func RemoveTitlesFromMarkdownLinks(r io.Reader, w io.Writer) error {
 	buf, err := io.ReadAll(r)
 	if err != nil {
 		return fmt.Errorf("RemoveTitlesFromMarkdownLinks: failed to read input: %w", err)
 	}
 
-	cleaned := markdownLinkWithTitleRegex.ReplaceAllFunc(buf, func(match []byte) []byte {
-		return markdownLinkWithTitleRegex.ReplaceAll(match, []byte("[$1]($2)"))
-	})
+	doc := parseMarkdownDocument(buf)
+	var edits []edit
+	for _, link := range doc.links() {
+		if link.image || link.reference {
+			continue
+		}
+		submatches := markdownLinkWithTitleRegex.FindStringSubmatch(string(buf[link.destStart:link.destStop]))
+		if submatches != nil {
+			edits = append(edits, edit{link.destStart, link.destStop, submatches[1]})
+		}
+	}
+	for _, definition := range doc.definitions {
+		if definition.titleStop > definition.titleStart {
+			edits = append(edits, edit{definition.titleStart, definition.titleStop, ""})
+		}
+	}
 
-	_, err = w.Write(cleaned)
+	_, err = w.Write(applyEdits(buf, edits))
 	if err != nil {
 		return fmt.Errorf("RemoveTitlesFromMarkdownLinks: failed to write output: %w", err)
 	}
 
 	return nil
 }
+
+// applyEdits returns content with the non-overlapping edits applied
+func applyEdits(content []byte, edits []edit) []byte {
+	if len(edits) == 0 {
+		return content
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var out strings.Builder
+	out.Grow(len(content))
+	last := 0
+	for _, e := range edits {
+		out.Write(content[last:e.start])
+		out.WriteString(e.text)
+		last = e.stop
+	}
+	out.Write(content[last:])
+	return []byte(out.String())
+}
+
+// links returns the outermost inline links, images and reference links in
+// the document. Brackets are matched with nesting, backslash-escaped brackets
+// are ignored and nothing inside code, HTML or reference definitions is
+// considered. As in CommonMark, a link whose text contains another link is
+// not a link; the inner one is. Reference links count only when their label
+// is defined.
+func (d *markdownDocument) links() []markdownLink {
+	content := d.content
+	closing := matchBrackets(content, d.skip)
+
+	var links []markdownLink
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\\' {
+			i++
+			continue
+		}
+		if content[i] != '[' {
+			continue
+		}
+		link, ok := d.parseLink(i, closing)
+		if !ok {
+			continue
+		}
+		if !link.image && d.containsLink(link.textStart, link.textStop) {
+			continue
+		}
+		links = append(links, link)
+		i = link.stop - 1
+	}
+	return links
+}
+
+// containsLink reports whether the text between start and stop holds a link
+func (d *markdownDocument) containsLink(start, stop int) bool {
+	text := &markdownDocument{content: d.content[start:stop], labels: d.labels}
+	for _, link := range text.links() {
+		if !link.image {
+			return true
+		}
+	}
+	return false
+}
+
+// autolinks returns the URL ranges of the autolinks in the document, without
+// the angle brackets
+func (d *markdownDocument) autolinks() []byteRange {
+	var ranges []byteRange
+	next := 0
+	for _, loc := range autolinkRegex.FindAllIndex(d.content, -1) {
+		if overlaps(d.skip, &next, loc[0], loc[1]) {
+			continue
+		}
+		ranges = append(ranges, byteRange{loc[0] + 1, loc[1] - 1})
+	}
+	return ranges
+}
+
+// matchBrackets maps the offset of every [ in prose to the offset of its
+// matching ]
+func matchBrackets(content []byte, skip []byteRange) map[int]int {
+	closing := make(map[int]int)
+	var open []int
+	next := 0
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '[':
+			if !overlaps(skip, &next, i, i+1) {
+				open = append(open, i)
+			}
+		case ']':
+			if len(open) > 0 && !overlaps(skip, &next, i, i+1) {
+				closing[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return closing
+}
+
+// parseLink parses a link whose text starts with the [ at offset open
+func (d *markdownDocument) parseLink(open int, closing map[int]int) (markdownLink, bool) {
+	content := d.content
+	textStop, ok := closing[open]
+	if !ok {
+		return markdownLink{}, false
+	}
+
+	link := markdownLink{
+		start:     open,
+		textStart: open + 1,
+		textStop:  textStop,
+	}
+	if open > 0 && content[open-1] == '!' && (open < 2 || content[open-2] != '\\') {
+		link.start--
+		link.image = true
+	}
+
+	after := textStop + 1
+	if after < len(content) && content[after] == '(' {
+		destStop, urlStart, urlStop, ok := scanLinkDestination(content, after+1)
+		if ok {
+			link.stop = destStop + 1
+			link.destStart = after + 1
+			link.destStop = destStop
+			link.urlStart = urlStart
+			link.urlStop = urlStop
+			return link, true
+		}
+	}
+
+	link.reference = true
+	label := content[link.textStart:link.textStop]
+	if labelStop, ok := closing[after]; ok && after < len(content) && content[after] == '[' {
+		// full [text][label] or collapsed [text][] reference
+		if labelStop > after+1 {
+			link.labelStart = after + 1
+			link.labelStop = labelStop
+			label = content[link.labelStart:link.labelStop]
+		}
+		link.stop = labelStop + 1
+	} else {
+		// shortcut [text] reference
+		link.stop = after
+	}
+	if !d.labels[normalizeLabel(label)] {
+		return markdownLink{}, false
+	}
+	return link, true
+}
+
+// scanLinkDestination returns the offset of the ) closing the destination and
+// optional title that start at offset i, along with the range of the URL
+func scanLinkDestination(content []byte, i int) (stop, urlStart, urlStop int, ok bool) {
+	i = skipLinkSpace(content, i)
+	if i >= len(content) {
+		return 0, 0, 0, false
+	}
+
+	if content[i] == '<' {
+		urlStart = i + 1
+		for i++; i < len(content) && content[i] != '>'; i++ {
+			if content[i] == '\n' || content[i] == '<' {
+				return 0, 0, 0, false
+			}
+			if content[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(content) {
+			return 0, 0, 0, false
+		}
+		urlStop = i
+		i++
+	} else {
+		urlStart = i
+		depth := 0
+		for ; i < len(content); i++ {
+			c := content[i]
+			if c == '\\' {
+				i++
+				continue
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if c <= ' ' {
+				break
+			}
+		}
+		if depth != 0 || i > len(content) {
+			return 0, 0, 0, false
+		}
+		urlStop = i
+	}
+
+	i = skipLinkSpace(content, i)
+	if i < len(content) && (content[i] == '"' || content[i] == '\'' || content[i] == '(') {
+		end := content[i]
+		if end == '(' {
+			end = ')'
+		}
+		for i++; i < len(content) && content[i] != end; i++ {
+			if content[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(content) {
+			return 0, 0, 0, false
+		}
+		i = skipLinkSpace(content, i+1)
+	}
+
+	if i >= len(content) || content[i] != ')' {
+		return 0, 0, 0, false
+	}
+	return i, urlStart, urlStop, true
+}
+
+func skipLinkSpace(content []byte, i int) int {
+	for i < len(content) && (content[i] == ' ' || content[i] == '\t' || content[i] == '\n' || content[i] == '\r') {
+		i++
+	}
+	return i
+}
@@ -69,6 +69,61 @@ This more text.
 Here is a link https://google.com.
 `,
 		},
+		{
+			name:     "Nested brackets in link text",
+			input:    "[  see [RFC 3986]  section 3 ](https://example.com/rfc)",
+			expected: "[see [RFC 3986] section 3](https://example.com/rfc)",
+		},
+		{
+			name:     "Escaped brackets in link text",
+			input:    "[  a \\] b  ](https://example.com) and \\[  not a link ](https://example.com)",
+			expected: "[a \\] b](https://example.com) and \\[  not a link ](https://example.com)",
+		},
+		{
+			name:     "Image is left alone",
+			input:    "![  alt   text ](https://example.com/a.png)",
+			expected: "![  alt   text ](https://example.com/a.png)",
+		},
+		{
+			name:     "Link wrapping an image",
+			input:    "[\n  ![badge](https://example.com/b.svg)\n](https://example.com)",
+			expected: "[![badge](https://example.com/b.svg)](https://example.com)",
+		},
+		{
+			name:     "Link inside link text is the link",
+			input:    "[outer [  inner ](https://example.com/i)](https://example.com/o)",
+			expected: "[outer [inner](https://example.com/i)](https://example.com/o)",
+		},
+		{
+			name:     "Destination with balanced parentheses",
+			input:    "[  Go  ](https://en.wikipedia.org/wiki/Go_(programming_language))",
+			expected: "[Go](https://en.wikipedia.org/wiki/Go_(programming_language))",
+		},
+		{
+			name:     "Reference links and definition labels",
+			input:    "[  Full  text ][ my   id ] and [ Collapsed ][] and [ shortcut ]\n\n[My Id]: https://a.example\n[ collapsed]: https://b.example\n[shortcut ]: https://c.example\n",
+			expected: "[Full text][my id] and [Collapsed][] and [shortcut]\n\n[My Id]: https://a.example\n[collapsed]: https://b.example\n[shortcut]: https://c.example\n",
+		},
+		{
+			name:     "Undefined reference is plain text",
+			input:    "[  not a link  ][nope]\n",
+			expected: "[  not a link  ][nope]\n",
+		},
+		{
+			name:     "Code spans in link text keep their whitespace",
+			input:    "[  `a   b`  ](https://example.com/a) [ run  `` x  ` y ``   now ](https://example.com/b) [ `  c  ` ](https://example.com/c)",
+			expected: "[`a   b`](https://example.com/a) [run `` x  ` y `` now](https://example.com/b) [`  c  `](https://example.com/c)",
+		},
+		{
+			name:     "Inline HTML in link text keeps its whitespace",
+			input:    "[  <span   title=\"a  b\">x</span>  ](https://example.com)",
+			expected: "[<span   title=\"a  b\">x</span>](https://example.com)",
+		},
+		{
+			name:     "Links in code are left alone",
+			input:    "`[  a  ](https://example.com)`\n\n```\n[  b  ](https://example.com)\n```\n",
+			expected: "`[  a  ](https://example.com)`\n\n```\n[  b  ](https://example.com)\n```\n",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -155,6 +210,51 @@ This more text.
 Here is a link https://google.com.
 `,
 		},
+		{
+			name:     "Single-quoted title",
+			input:    `[Google](https://google.com 'Search Engine')`,
+			expected: `[Google](https://google.com)`,
+		},
+		{
+			name:     "Parenthesized title",
+			input:    `[Google](https://google.com (Search Engine))`,
+			expected: `[Google](https://google.com)`,
+		},
+		{
+			name:     "Title with escaped quote",
+			input:    `[Google](https://google.com "The \"best\" engine")`,
+			expected: `[Google](https://google.com)`,
+		},
+		{
+			name:     "Angle-bracketed destination",
+			input:    `[Doc](<https://example.com/a b> "Doc")`,
+			expected: `[Doc](<https://example.com/a b>)`,
+		},
+		{
+			name:     "Destination with parentheses and no title",
+			input:    `[Go](https://en.wikipedia.org/wiki/Go_(programming_language))`,
+			expected: `[Go](https://en.wikipedia.org/wiki/Go_(programming_language))`,
+		},
+		{
+			name:     "Image title is kept",
+			input:    `![Logo](https://example.com/logo.png "Logo")`,
+			expected: `![Logo](https://example.com/logo.png "Logo")`,
+		},
+		{
+			name:     "Reference definitions",
+			input:    "[a]: https://a.example \"A\"\n[b]: <https://b.example> 'B'\n[c]: https://c.example\n  (C)\n[d]: https://d.example\n\n[a] [b] [c] [d]\n",
+			expected: "[a]: https://a.example\n[b]: <https://b.example>\n[c]: https://c.example\n[d]: https://d.example\n\n[a] [b] [c] [d]\n",
+		},
+		{
+			name:     "Reference definition title followed by text is not a title",
+			input:    "[a]: https://a.example \"A\" trailing\n",
+			expected: "[a]: https://a.example \"A\" trailing\n",
+		},
+		{
+			name:     "Nested brackets in link text",
+			input:    `[see [RFC 3986]](https://example.com/rfc "RFC")`,
+			expected: `[see [RFC 3986]](https://example.com/rfc)`,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,222 @@
+package links
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// orgProtectedBlocks are the Org blocks whose content is code or verbatim text
+var orgProtectedBlocks = []string{"src", "example", "export"}
+
+// orgLink is an Org bracket link, [[target][description]] or [[target]], as
+// byte offsets into the document. The description range is empty for links
+// without one.
+type orgLink struct {
+	start, stop             int
+	targetStart, targetStop int
+	descStart, descStop     int
+}
+
+// orgDocument is an Org document with its code and verbatim text and links
+type orgDocument struct {
+	content []byte
+	skip    []byteRange
+	links   []orgLink
+}
+
+func parseOrgDocument(content []byte) *orgDocument {
+	doc := &orgDocument{content: content, skip: orgLiteralRanges(content)}
+	doc.links = findOrgLinks(content, doc.skip)
+	return doc
+}
+
+// orgLiteralRanges returns the sorted ranges of an Org document that hold
+// code or verbatim text: source, example and export blocks, from the start of
+// the #+BEGIN line to the end of the matching #+END line, fixed-width lines
+// starting with a colon and ~code~ and =verbatim= markup. Keywords are case
+// insensitive. An unterminated block runs to the end of the document.
+func orgLiteralRanges(content []byte) []byteRange {
+	var ranges []byteRange
+	block := ""
+	blockStart := 0
+	for lineStart := 0; lineStart < len(content); {
+		lineStop := endOfLine(content, lineStart)
+		trimmed := bytes.TrimSpace(content[lineStart:lineStop])
+		keyword := strings.ToLower(string(trimmed))
+
+		if block == "" {
+			if bytes.Equal(trimmed, []byte(":")) || bytes.HasPrefix(trimmed, []byte(": ")) {
+				ranges = append(ranges, byteRange{lineStart, lineStop})
+			}
+			for _, name := range orgProtectedBlocks {
+				if isOrgKeyword(keyword, "#+begin_"+name) {
+					block = name
+					blockStart = lineStart
+					break
+				}
+			}
+		} else if isOrgKeyword(keyword, "#+end_"+block) {
+			ranges = append(ranges, byteRange{blockStart, lineStop})
+			block = ""
+		}
+
+		lineStart = lineStop + 1
+	}
+	if block != "" {
+		ranges = append(ranges, byteRange{blockStart, len(content)})
+	}
+
+	blocks := ranges
+	next := 0
+	for i := 0; i < len(content); i++ {
+		stop := orgInlineCodeEnd(content, i)
+		if stop < 0 || overlaps(blocks, &next, i, i+1) {
+			continue
+		}
+		ranges = append(ranges, byteRange{i, stop})
+		i = stop - 1
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges
+}
+
+// orgInlineCodeEnd returns the end of the ~code~ or =verbatim= markup that
+// starts at offset start, or -1 if there is none. As in Org, the markers must
+// not be next to whitespace on the inside, must be preceded and followed by
+// whitespace or punctuation on the outside, and the markup spans at most two
+// lines.
+func orgInlineCodeEnd(content []byte, start int) int {
+	marker := content[start]
+	if marker != '~' && marker != '=' {
+		return -1
+	}
+	if start > 0 && !isSpaceByte(content[start-1]) && !strings.ContainsRune(`-({'"`, rune(content[start-1])) {
+		return -1
+	}
+	if start+1 >= len(content) || isSpaceByte(content[start+1]) {
+		return -1
+	}
+	newlines := 0
+	for i := start + 2; i < len(content); i++ {
+		switch {
+		case content[i] == '\n':
+			newlines++
+			if newlines > 1 {
+				return -1
+			}
+		case content[i] == marker && !isSpaceByte(content[i-1]) &&
+			(i+1 == len(content) || isSpaceByte(content[i+1]) || strings.ContainsRune(`-.,;:!?')}["\`, rune(content[i+1]))):
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// isOrgKeyword reports whether line starts with keyword followed by nothing
+// or whitespace
+func isOrgKeyword(line, keyword string) bool {
+	return strings.HasPrefix(line, keyword) &&
+		(len(line) == len(keyword) || line[len(keyword)] == ' ' || line[len(keyword)] == '\t')
+}
+
+// findOrgLinks returns the bracket links in content outside of the skipped
+// ranges. Link targets end at the first unescaped ] and cannot span lines;
+// descriptions end at the first ]].
+func findOrgLinks(content []byte, skip []byteRange) []orgLink {
+	var links []orgLink
+	next := 0
+	for i := 0; i+1 < len(content); i++ {
+		if content[i] != '[' || content[i+1] != '[' || overlaps(skip, &next, i, i+2) {
+			continue
+		}
+
+		link := orgLink{start: i, targetStart: i + 2}
+		j := link.targetStart
+		for ; j < len(content) && content[j] != ']' && content[j] != '\n'; j++ {
+			if content[j] == '\\' && j+1 < len(content) {
+				j++
+			}
+		}
+		if j+1 >= len(content) || content[j] != ']' || j == link.targetStart {
+			continue
+		}
+		link.targetStop = j
+
+		switch content[j+1] {
+		case ']':
+			link.stop = j + 2
+		case '[':
+			end := bytes.Index(content[j+2:], []byte("]]"))
+			if end < 0 {
+				continue
+			}
+			link.descStart = j + 2
+			link.descStop = j + 2 + end
+			link.stop = link.descStop + 2
+		default:
+			continue
+		}
+
+		links = append(links, link)
+		i = link.stop - 1
+	}
+	return links
+}
+
+// FindOrgURLs returns every URL in an Org document, in document order. Link
+// targets are found from the bracket link syntax, so their exact extent is
+// known, unless they contain whitespace; other URLs are found in the
+// remaining text. URLs in source, example and export blocks, fixed-width
+// lines and ~code~ and =verbatim= markup are skipped.
+func FindOrgURLs(content []byte) []URLSite {
+	m := strictURLMatcher()
+	doc := parseOrgDocument(content)
+
+	var targets []URLSite
+	for _, link := range doc.links {
+		target := string(content[link.targetStart:link.targetStop])
+		if strings.ContainsFunc(target, unicode.IsSpace) {
+			// left to the prose scan so that the spaces are not re-encoded
+			continue
+		}
+		if locs := m.findAll(target); len(locs) == 0 || locs[0][0] != 0 {
+			// internal links such as [[*Heading]] and file links
+			continue
+		}
+		targets = append(targets, URLSite{URL: target, Offset: link.targetStart, Syntax: SyntaxOrgLink})
+	}
+
+	return withBareURLs(content, doc.skip, targets)
+}
+
+// RemoveWhitespaceFromOrgLinks collapses whitespace in the descriptions of
+// Org bracket links, as RemoveWhitespaceFromMarkdownLinks does for the text
+// of Markdown links.
+func RemoveWhitespaceFromOrgLinks(r io.Reader, w io.Writer) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("RemoveWhitespaceFromOrgLinks: failed to read input: %w", err)
+	}
+
+	var edits []edit
+	for _, link := range parseOrgDocument(buf).links {
+		if link.descStop > link.descStart {
+			description := whitespaceRegex.ReplaceAllString(string(buf[link.descStart:link.descStop]), " ")
+			edits = append(edits, edit{link.descStart, link.descStop, strings.TrimSpace(description)})
+		}
+	}
+
+	_, err = w.Write(applyEdits(buf, edits))
+	if err != nil {
+		return fmt.Errorf("RemoveWhitespaceFromOrgLinks: failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package links
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteOrg(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Bracket link with description",
+			input:    "See [[https://example.com/a?utm_source=x&id=1][the page]].\n",
+			expected: "See [[https://example.com/a?id=1][the page]].\n",
+		},
+		{
+			name:     "Bracket link without description",
+			input:    "[[https://example.com/a?utm_source=x]]\n",
+			expected: "[[https://example.com/a]]\n",
+		},
+		{
+			name:     "URL in a description",
+			input:    "[[https://example.com/a][https://example.com/a?utm_source=x]]\n",
+			expected: "[[https://example.com/a][https://example.com/a]]\n",
+		},
+		{
+			name:     "Bare URL",
+			input:    "* Heading https://example.com/?utm_source=x\n",
+			expected: "* Heading https://example.com/\n",
+		},
+		{
+			name:     "Targets with spaces are not re-encoded",
+			input:    "[[https://example.com/a b][x]] [[https://example.com/c?utm_source=x d]]\n",
+			expected: "[[https://example.com/a b][x]] [[https://example.com/c d]]\n",
+		},
+		{
+			name:     "Internal links are left alone",
+			input:    "[[*Heading][heading]] [[file:notes.org]]\n",
+			expected: "[[*Heading][heading]] [[file:notes.org]]\n",
+		},
+		{
+			name: "Source blocks are left alone",
+			input: "#+BEGIN_SRC sh\ncurl https://example.com/?utm_source=x\n#+END_SRC\n" +
+				"https://example.com/?utm_source=y\n",
+			expected: "#+BEGIN_SRC sh\ncurl https://example.com/?utm_source=x\n#+END_SRC\n" +
+				"https://example.com/\n",
+		},
+		{
+			name: "Example blocks are left alone, whatever their case",
+			input: "  #+begin_example\n  [[https://example.com/?utm_source=x]]\n  #+end_example\n" +
+				"[[https://example.com/?utm_source=y]]\n",
+			expected: "  #+begin_example\n  [[https://example.com/?utm_source=x]]\n  #+end_example\n" +
+				"[[https://example.com/]]\n",
+		},
+		{
+			name:     "An end keyword must match its block",
+			input:    "#+BEGIN_SRC\n#+END_EXAMPLE\nhttps://example.com/?utm_source=x\n#+END_SRC\n",
+			expected: "#+BEGIN_SRC\n#+END_EXAMPLE\nhttps://example.com/?utm_source=x\n#+END_SRC\n",
+		},
+		{
+			name:     "Inline code and verbatim are left alone",
+			input:    "Run ~curl https://example.com/?utm_source=x~ or (=https://example.com/?utm_source=y=), see https://example.com/?utm_source=z\n",
+			expected: "Run ~curl https://example.com/?utm_source=x~ or (=https://example.com/?utm_source=y=), see https://example.com/\n",
+		},
+		{
+			name:     "Markers inside words are not markup",
+			input:    "a~b https://example.com/?utm_source=x&id=1 c~d\n",
+			expected: "a~b https://example.com/?id=1 c~d\n",
+		},
+		{
+			name:     "Fixed-width lines are left alone",
+			input:    "  : curl https://example.com/?utm_source=x\n:\n:not fixed-width https://example.com/?utm_source=y\n",
+			expected: "  : curl https://example.com/?utm_source=x\n:\n:not fixed-width https://example.com/\n",
+		},
+		{
+			name:     "Unterminated block runs to the end",
+			input:    "#+BEGIN_SRC\nhttps://example.com/?utm_source=x\n",
+			expected: "#+BEGIN_SRC\nhttps://example.com/?utm_source=x\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), OrgURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindOrgURLs(t *testing.T) {
+	content := "[[https://a.example/][a]]\nsee https://b.example/\n"
+
+	expected := []URLSite{
+		{URL: "https://a.example/", Offset: 2, Line: 1, Column: 3, Syntax: SyntaxOrgLink},
+		{URL: "https://b.example/", Offset: 30, Line: 2, Column: 5, Syntax: SyntaxBare},
+	}
+
+	result := FindOrgURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestRemoveWhitespaceFromOrgLinks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Description spanning lines",
+			input:    "[[https://example.com][  the\n   page  ]]\n",
+			expected: "[[https://example.com][the page]]\n",
+		},
+		{
+			name:     "Link without description",
+			input:    "[[https://example.com]]  and  more\n",
+			expected: "[[https://example.com]]  and  more\n",
+		},
+		{
+			name:     "Source blocks are left alone",
+			input:    "#+BEGIN_SRC org\n[[https://example.com][  a  ]]\n#+END_SRC\n",
+			expected: "#+BEGIN_SRC org\n[[https://example.com][  a  ]]\n#+END_SRC\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := RemoveWhitespaceFromOrgLinks(bytes.NewBufferString(tc.input), &out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, out.String()); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
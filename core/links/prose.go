@@ -0,0 +1,101 @@
+package links
+
+import (
+	"sort"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// byteRange is a half-open range of byte offsets in a document
+type byteRange struct {
+	start, stop int
+}
+
+// nonProseRanges returns the sorted byte ranges of a Markdown document whose
+// text must not be searched for URLs: fenced and indented code blocks, code
+// spans, HTML blocks and inline HTML, including comments. The ranges holding
+// HTML are also returned on their own, so that their tags can be searched for
+// URL attributes. The document is parsed as CommonMark, so any fence length,
+// ~~~ fences and nested fences are handled the way renderers handle them.
+func nonProseRanges(content []byte) (skip, html []byteRange) {
+	var ranges []byteRange
+	addSegment := func(s text.Segment) {
+		if s.Stop > s.Start {
+			ranges = append(ranges, byteRange{s.Start, s.Stop})
+		}
+	}
+	addLines := func(lines *text.Segments) {
+		for i := 0; i < lines.Len(); i++ {
+			addSegment(lines.At(i))
+		}
+	}
+	// a tag may span several lines, so HTML is kept as one range per node
+	addHTML := func(lines *text.Segments, closure text.Segment) {
+		addLines(lines)
+		addSegment(closure)
+		if lines.Len() == 0 {
+			return
+		}
+		r := byteRange{lines.At(0).Start, lines.At(lines.Len() - 1).Stop}
+		if closure.Stop > r.stop {
+			r.stop = closure.Stop
+		}
+		html = append(html, r)
+	}
+
+	doc := goldmark.DefaultParser().Parse(text.NewReader(content))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock:
+			if n.Info != nil {
+				addSegment(n.Info.Segment)
+			}
+			addLines(n.Lines())
+		case *ast.CodeBlock:
+			addLines(n.Lines())
+		case *ast.HTMLBlock:
+			var closure text.Segment
+			if n.HasClosure() {
+				closure = n.ClosureLine
+			}
+			addHTML(n.Lines(), closure)
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					addSegment(t.Segment)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			addHTML(n.Segments, text.Segment{})
+		}
+		return ast.WalkContinue, nil
+	})
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges, html
+}
+
+// overlaps reports whether [start, stop) intersects any of the sorted ranges.
+// next is the index of the first range that may still intersect and is
+// advanced past ranges that end before start, so that callers visiting
+// positions in document order scan the ranges only once.
+func overlaps(ranges []byteRange, next *int, start, stop int) bool {
+	for *next < len(ranges) && ranges[*next].stop <= start {
+		*next++
+	}
+	for i := *next; i < len(ranges) && ranges[i].start < stop; i++ {
+		if ranges[i].stop > start {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package links
+
+import (
+	"bytes"
+	"strings"
+)
+
+// referenceDefinition is a link reference definition, [label]: url "title".
+// All fields are byte offsets into the document. The destination excludes
+// angle brackets; the title range runs from the end of the destination,
+// including any angle bracket, to the end of the definition, so that removing
+// it leaves a valid definition. It is empty when there is no title.
+type referenceDefinition struct {
+	start, stop           int
+	labelStart, labelStop int
+	destStart, destStop   int
+	titleStart, titleStop int
+}
+
+// findReferenceDefinitions returns the link reference definitions in content
+// that start outside of the skipped ranges. As in CommonMark, a definition
+// cannot interrupt a paragraph, so it must follow a blank line, another
+// definition, a heading or a block that was skipped.
+func findReferenceDefinitions(content []byte, skip []byteRange) []referenceDefinition {
+	var definitions []referenceDefinition
+	next := 0
+	canStart := true
+	for lineStart := 0; lineStart < len(content); {
+		lineStop := bytes.IndexByte(content[lineStart:], '\n')
+		if lineStop < 0 {
+			lineStop = len(content)
+		} else {
+			lineStop += lineStart
+		}
+		line := content[lineStart:lineStop]
+
+		if overlaps(skip, &next, lineStart, lineStop) {
+			lineStart = lineStop + 1
+			canStart = true
+			continue
+		}
+
+		if canStart {
+			if definition, ok := parseReferenceDefinition(content, lineStart); ok {
+				definitions = append(definitions, definition)
+				lineStart = definition.stop + 1
+				continue
+			}
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		canStart = len(trimmed) == 0 || trimmed[0] == '#'
+		lineStart = lineStop + 1
+	}
+	return definitions
+}
+
+// parseReferenceDefinition parses a definition starting at the beginning of
+// the line at offset start
+func parseReferenceDefinition(content []byte, start int) (referenceDefinition, bool) {
+	i := start
+	for indent := 0; indent < 3 && i < len(content) && content[i] == ' '; indent++ {
+		i++
+	}
+	if i >= len(content) || content[i] != '[' {
+		return referenceDefinition{}, false
+	}
+
+	definition := referenceDefinition{start: start, labelStart: i + 1}
+	for i++; i < len(content) && content[i] != ']'; i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '[':
+			return referenceDefinition{}, false
+		}
+	}
+	if i+1 >= len(content) || content[i+1] != ':' || len(bytes.TrimSpace(content[definition.labelStart:i])) == 0 {
+		return referenceDefinition{}, false
+	}
+	definition.labelStop = i
+
+	i, ok := skipDefinitionSpace(content, i+2)
+	if !ok || i >= len(content) {
+		return referenceDefinition{}, false
+	}
+
+	if content[i] == '<' {
+		definition.destStart = i + 1
+		for i++; i < len(content) && content[i] != '>'; i++ {
+			if content[i] == '\n' || content[i] == '<' {
+				return referenceDefinition{}, false
+			}
+			if content[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(content) {
+			return referenceDefinition{}, false
+		}
+		definition.destStop = i
+		i++
+	} else {
+		definition.destStart = i
+		for i < len(content) && content[i] > ' ' {
+			i++
+		}
+		definition.destStop = i
+		if definition.destStop == definition.destStart {
+			return referenceDefinition{}, false
+		}
+	}
+	destEnd := i
+
+	// a title must be separated from the destination by whitespace and be
+	// followed by nothing but whitespace on its line
+	if titleStop, ok := parseDefinitionTitle(content, destEnd); ok {
+		definition.titleStart = destEnd
+		definition.titleStop = titleStop
+		definition.stop = titleStop
+		return definition, true
+	}
+
+	lineStop := endOfLine(content, destEnd)
+	if len(bytes.TrimSpace(content[destEnd:lineStop])) != 0 {
+		return referenceDefinition{}, false
+	}
+	definition.titleStart = destEnd
+	definition.titleStop = destEnd
+	definition.stop = lineStop
+	return definition, true
+}
+
+// parseDefinitionTitle returns the end of the line holding a title that
+// follows a destination ending at offset i
+func parseDefinitionTitle(content []byte, i int) (int, bool) {
+	j, ok := skipDefinitionSpace(content, i)
+	if !ok || j == i || j >= len(content) {
+		return 0, false
+	}
+
+	end := content[j]
+	switch end {
+	case '"', '\'':
+	case '(':
+		end = ')'
+	default:
+		return 0, false
+	}
+	for j++; j < len(content) && content[j] != end; j++ {
+		if content[j] == '\\' {
+			j++
+		}
+	}
+	if j >= len(content) {
+		return 0, false
+	}
+
+	lineStop := endOfLine(content, j+1)
+	if len(bytes.TrimSpace(content[j+1:lineStop])) != 0 {
+		return 0, false
+	}
+	return lineStop, true
+}
+
+// skipDefinitionSpace skips spaces and tabs and at most one line ending
+func skipDefinitionSpace(content []byte, i int) (int, bool) {
+	newline := false
+	for i < len(content) {
+		switch content[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			if newline {
+				return i, false
+			}
+			newline = true
+		default:
+			return i, true
+		}
+		i++
+	}
+	return i, true
+}
+
+func endOfLine(content []byte, i int) int {
+	if stop := bytes.IndexByte(content[i:], '\n'); stop >= 0 {
+		return i + stop
+	}
+	return len(content)
+}
+
+// normalizeLabel returns the form in which link labels are compared:
+// whitespace collapsed, surrounding whitespace removed and case folded
+func normalizeLabel(label []byte) string {
+	return strings.ToLower(strings.Join(strings.Fields(string(label)), " "))
+}
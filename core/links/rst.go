@@ -0,0 +1,122 @@
+package links
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// rstDirectiveRegex matches a directive line, .. name::, with the
+	// directive's indentation as the first group and its name as the second
+	rstDirectiveRegex = regexp.MustCompile(`^([ \t]*)\.\.[ \t]+([A-Za-z0-9_-]+)::`)
+	// rstLinkRegex matches an embedded URI link, `text <url>`_ or the
+	// anonymous `text <url>`__. The first group is the URL.
+	rstLinkRegex = regexp.MustCompile("`[^`]*<([^<>`\\s]+)>`__?")
+)
+
+// rstCodeDirectives are the directives whose content is code
+var rstCodeDirectives = []string{"code", "code-block", "raw", "sourcecode"}
+
+// rstLiteralRanges returns the sorted ranges of a reStructuredText document
+// that hold code or literal text: code directives with their content, literal
+// blocks introduced by a paragraph ending in :: and “inline literals“.
+func rstLiteralRanges(content []byte) []byteRange {
+	var ranges []byteRange
+	for lineStart := 0; lineStart < len(content); {
+		lineStop := endOfLine(content, lineStart)
+		line := content[lineStart:lineStop]
+
+		if m := rstDirectiveRegex.FindSubmatch(line); m != nil {
+			if slices.Contains(rstCodeDirectives, strings.ToLower(string(m[2]))) {
+				stop := indentedBlockEnd(content, lineStop, len(m[1]))
+				ranges = append(ranges, byteRange{lineStart, stop})
+				lineStop = stop
+			}
+		} else if bytes.HasSuffix(bytes.TrimSpace(line), []byte("::")) {
+			if stop := indentedBlockEnd(content, lineStop, indentWidth(line)); stop > lineStop {
+				ranges = append(ranges, byteRange{lineStop, stop})
+				lineStop = stop
+			}
+		}
+
+		lineStart = lineStop + 1
+	}
+
+	blocks := ranges
+	next := 0
+	for i := 0; i < len(content); {
+		start := bytes.Index(content[i:], []byte("``"))
+		if start < 0 {
+			break
+		}
+		start += i
+		end := bytes.Index(content[start+2:], []byte("``"))
+		if end < 0 {
+			break
+		}
+		stop := start + 2 + end + 2
+		if !overlaps(blocks, &next, start, start+2) {
+			ranges = append(ranges, byteRange{start, stop})
+			i = stop
+		} else {
+			i = start + 2
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges
+}
+
+// indentedBlockEnd returns the end of the block of lines, more indented than
+// indent, that follows the line ending at offset lineStop. Blank lines within
+// the block belong to it. It is lineStop when no such line follows.
+func indentedBlockEnd(content []byte, lineStop, indent int) int {
+	end := lineStop
+	for lineStart := lineStop + 1; lineStart < len(content); {
+		stop := endOfLine(content, lineStart)
+		line := content[lineStart:stop]
+		if len(bytes.TrimSpace(line)) > 0 {
+			if indentWidth(line) <= indent {
+				break
+			}
+			end = stop
+		}
+		lineStart = stop + 1
+	}
+	return end
+}
+
+// indentWidth returns the number of spaces and tabs a line starts with
+func indentWidth(line []byte) int {
+	return len(line) - len(bytes.TrimLeft(line, " \t"))
+}
+
+// FindRSTURLs returns every URL in a reStructuredText document, in document
+// order. The targets of embedded URI links are found from the link syntax, so
+// their exact extent is known; other URLs are found in the remaining text.
+// URLs in code directives, literal blocks and inline literals are skipped.
+func FindRSTURLs(content []byte) []URLSite {
+	m := strictURLMatcher()
+	skip := rstLiteralRanges(content)
+
+	var targets []URLSite
+	next := 0
+	for _, loc := range rstLinkRegex.FindAllSubmatchIndex(content, -1) {
+		if overlaps(skip, &next, loc[0], loc[1]) {
+			continue
+		}
+		target := string(content[loc[2]:loc[3]])
+		if locs := m.findAll(target); len(locs) == 0 || locs[0][0] != 0 {
+			// references to internal targets such as `text <target_>`_
+			continue
+		}
+		targets = append(targets, URLSite{URL: target, Offset: loc[2], Syntax: SyntaxRSTLink})
+	}
+
+	return withBareURLs(content, skip, targets)
+}
@@ -0,0 +1,87 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteRST(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Embedded URI link",
+			input:    "See `the page <https://example.com/a?utm_source=x&id=1>`_.\n",
+			expected: "See `the page <https://example.com/a?id=1>`_.\n",
+		},
+		{
+			name:     "Anonymous link",
+			input:    "`page <https://example.com/?utm_source=x>`__\n",
+			expected: "`page <https://example.com/>`__\n",
+		},
+		{
+			name:     "Hyperlink target and bare URL",
+			input:    ".. _page: https://example.com/a?utm_source=x\n\nhttps://example.com/b?utm_source=y\n",
+			expected: ".. _page: https://example.com/a\n\nhttps://example.com/b\n",
+		},
+		{
+			name: "Code block directive",
+			input: ".. code-block:: sh\n\n   curl https://example.com/?utm_source=x\n\n" +
+				"https://example.com/?utm_source=y\n",
+			expected: ".. code-block:: sh\n\n   curl https://example.com/?utm_source=x\n\n" +
+				"https://example.com/\n",
+		},
+		{
+			name:     "Other directives are prose",
+			input:    ".. note::\n\n   https://example.com/?utm_source=x\n",
+			expected: ".. note::\n\n   https://example.com/\n",
+		},
+		{
+			name: "Literal block after a paragraph ending in ::",
+			input: "Run this https://example.com/a?utm_source=x::\n\n    curl https://example.com/b?utm_source=y\n\n" +
+				"Then https://example.com/c?utm_source=z\n",
+			expected: "Run this https://example.com/a::\n\n    curl https://example.com/b?utm_source=y\n\n" +
+				"Then https://example.com/c\n",
+		},
+		{
+			name:     "Inline literal",
+			input:    "Use ``https://example.com/?utm_source=x`` or https://example.com/?utm_source=y\n",
+			expected: "Use ``https://example.com/?utm_source=x`` or https://example.com/\n",
+		},
+		{
+			name:     "Internal targets are left alone",
+			input:    "`Intro <intro_>`_\n",
+			expected: "`Intro <intro_>`_\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), RSTURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindRSTURLs(t *testing.T) {
+	content := "`a <https://a.example/>`_\nsee https://b.example/\n"
+
+	expected := []URLSite{
+		{URL: "https://a.example/", Offset: 4, Line: 1, Column: 5, Syntax: SyntaxRSTLink},
+		{URL: "https://b.example/", Offset: 30, Line: 2, Column: 5, Syntax: SyntaxBare},
+	}
+
+	result := FindRSTURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,243 @@
+package links
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultSiteRules holds the built-in site rules
+//
+//go:embed sites.yaml
+var defaultSiteRules []byte
+
+// builtinSites are the transforms of the built-in site rules, in order
+var builtinSites = newBuiltinSites()
+
+// siteRuleNameRegex matches the names rules may have, which become config
+// keys and flags
+var siteRuleNameRegex = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// SiteRule declares how the URLs of a site are cleaned. A rule applies to
+// the hosts its HostMatcher matches, outside the paths it excludes, and
+// removes the query parameters it names or whose names match one of its
+// patterns, the path segments starting with one of its prefixes, and trailing
+// slashes as its policy says. On the paths of its keep_params, it removes
+// every query parameter they do not list instead.
+type SiteRule struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	HostMatcher `yaml:",inline"`
+
+	ExcludePathPrefixes []string `yaml:"exclude_path_prefixes"` // paths the rule leaves alone
+
+	Params         []string `yaml:"params"`          // names of query parameters to remove
+	ParamPatterns  []string `yaml:"param_patterns"`  // regular expressions for names of query parameters to remove
+	AllParams      bool     `yaml:"all_params"`      // remove the whole query
+	NormalizeQuery bool     `yaml:"normalize_query"` // always re-encode the query, sorting its parameters
+
+	KeepParams []KeepParams `yaml:"keep_params"` // the only query parameters kept on some paths
+
+	PathSegmentPrefixes []string `yaml:"path_segment_prefixes"` // remove path segments that start with any of these
+	TrailingSlash       string   `yaml:"trailing_slash"`        // "add", "strip" or "keep", the default
+}
+
+// KeepParams lists the only query parameters kept on the paths that start
+// with one of its prefixes, for pages whose tracking parameters change too
+// often to list. Prefixes match whole path segments, so /s matches /s and
+// /s/ref but not /stores.
+type KeepParams struct {
+	PathPrefixes []string `yaml:"path_prefixes"` // every path if empty
+	Params       []string `yaml:"params"`
+}
+
+// matchesPath reports whether the parameters of path are restricted
+func (k KeepParams) matchesPath(path string) bool {
+	return len(k.PathPrefixes) == 0 || hasPathPrefix(path, k.PathPrefixes)
+}
+
+// hasPathPrefix reports whether path starts with one of prefixes, matching
+// whole path segments
+func hasPathPrefix(path string, prefixes []string) bool {
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		prefix = strings.TrimSuffix(prefix, "/")
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	})
+}
+
+// removes reports whether param is not one of the parameters kept
+func (k KeepParams) removes(param string) bool {
+	return !slices.Contains(k.Params, param)
+}
+
+// ParseSiteRules parses site rules from YAML or JSON, a document with the
+// rules listed under sites. Unknown fields are an error, so that typos do not
+// silently disable part of a rule.
+func ParseSiteRules(data []byte) ([]SiteRule, error) {
+	var document struct {
+		Sites []SiteRule `yaml:"sites"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid site rules: %w", err)
+	}
+	for _, rule := range document.Sites {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return document.Sites, nil
+}
+
+// DefaultSiteRules returns the built-in site rules.
+func DefaultSiteRules() []SiteRule {
+	rules, err := ParseSiteRules(defaultSiteRules)
+	if err != nil {
+		panic(fmt.Sprintf("built-in site rules: %v", err))
+	}
+	return rules
+}
+
+func (r SiteRule) validate() error {
+	if !siteRuleNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("site rule %q: name must be lowercase letters, digits and dashes", r.Name)
+	}
+	if r.HostMatcher.empty() {
+		return fmt.Errorf("site rule %q: no domains, hosts or subdomains_of to match", r.Name)
+	}
+	if err := r.HostMatcher.validate(); err != nil {
+		return fmt.Errorf("site rule %q: %w", r.Name, err)
+	}
+	for _, pattern := range r.ParamPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("site rule %q: invalid param pattern: %w", r.Name, err)
+		}
+	}
+	for _, prefix := range r.ExcludePathPrefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("site rule %q: exclude_path_prefixes prefix %q must start with /", r.Name, prefix)
+		}
+	}
+	for _, keep := range r.KeepParams {
+		if len(keep.Params) == 0 {
+			return fmt.Errorf("site rule %q: keep_params without params, use all_params to remove the whole query", r.Name)
+		}
+		for _, prefix := range keep.PathPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("site rule %q: keep_params path prefix %q must start with /", r.Name, prefix)
+			}
+		}
+	}
+	switch r.TrailingSlash {
+	case "", "keep", "add", "strip":
+	default:
+		return fmt.Errorf("site rule %q: trailing_slash must be add, strip or keep, not %q", r.Name, r.TrailingSlash)
+	}
+	return nil
+}
+
+// NewSiteTransform returns the Transform a site rule declares.
+func NewSiteTransform(rule SiteRule) (Transform, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(rule.ParamPatterns))
+	for _, pattern := range rule.ParamPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+	description := rule.Description
+	if description == "" {
+		description = rule.Name + " URL parameter removal"
+	}
+
+	isTracking := func(param string) bool {
+		return slices.Contains(rule.Params, param) ||
+			slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool { return re.MatchString(param) })
+	}
+
+	clean := func(u *url.URL) {
+		if hasPathPrefix(u.Path, rule.ExcludePathPrefixes) {
+			return
+		}
+		if len(rule.PathSegmentPrefixes) > 0 {
+			segments := strings.Split(u.Path, "/")
+			segments = slices.DeleteFunc(segments, func(segment string) bool {
+				return slices.ContainsFunc(rule.PathSegmentPrefixes, func(prefix string) bool {
+					return strings.HasPrefix(segment, prefix)
+				})
+			})
+			u.Path = strings.Join(segments, "/")
+		}
+
+		shouldRemove := isTracking
+		if i := slices.IndexFunc(rule.KeepParams, func(k KeepParams) bool { return k.matchesPath(u.Path) }); i >= 0 {
+			shouldRemove = rule.KeepParams[i].removes
+		}
+
+		switch {
+		case rule.AllParams:
+			u.RawQuery = ""
+		case rule.NormalizeQuery:
+			q := u.Query()
+			for param := range q {
+				if shouldRemove(param) {
+					q.Del(param)
+				}
+			}
+			u.RawQuery = q.Encode()
+		default:
+			removeQueryParams(u, shouldRemove)
+		}
+
+		switch rule.TrailingSlash {
+		case "add":
+			u.Path = addTrailingSlash(u.Path)
+		case "strip":
+			u.Path = stripTrailingSlash(u.Path)
+		}
+	}
+
+	return NewTransform(rule.Name, description, rule.HostMatcher.Match, clean), nil
+}
+
+func newBuiltinSites() []Transform {
+	var transforms []Transform
+	for _, rule := range DefaultSiteRules() {
+		transform, err := NewSiteTransform(rule)
+		if err != nil {
+			panic(fmt.Sprintf("built-in site rules: %v", err))
+		}
+		transforms = append(transforms, transform)
+	}
+	return transforms
+}
+
+// mustSiteTransform returns the Transform of a site rule declared in Go
+func mustSiteTransform(rule SiteRule) Transform {
+	transform, err := NewSiteTransform(rule)
+	if err != nil {
+		panic(fmt.Sprintf("built-in site rule: %v", err))
+	}
+	return transform
+}
+
+// defaultSite returns the transform of the built-in site rule named name
+func defaultSite(name string) Transform {
+	for _, transform := range builtinSites {
+		if transform.Name() == name {
+			return transform
+		}
+	}
+	panic(fmt.Sprintf("no built-in site rule %q", name))
+}
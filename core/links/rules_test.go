@@ -0,0 +1,140 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSiteTransform(t *testing.T) {
+	rules, err := ParseSiteRules([]byte(`
+sites:
+  - name: example
+    domains: [example.com]
+    hosts: [shop.example.net]
+    params: [ref_id]
+    param_patterns: ['^trk\d+$']
+    keep_params:
+      - path_prefixes: [/search]
+        params: [q]
+    path_segment_prefixes: [sr=]
+    exclude_path_prefixes: [/raw]
+    trailing_slash: add
+  - name: all-params
+    subdomains_of: [example.org]
+    all_params: true
+    trailing_slash: strip
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	example, err := NewSiteTransform(rules[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	allParams, err := NewSiteTransform(rules[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		transform Transform
+		input     string
+		expected  string
+	}{
+		{
+			name:      "Params, patterns and path segments",
+			transform: example,
+			input:     "https://www.example.com/a/sr=1/b?ref_id=1&trk12=x&trk=y",
+			expected:  "https://www.example.com/a/b/?trk=y",
+		},
+		{
+			name:      "Keep only listed params",
+			transform: example,
+			input:     "https://www.example.com/search?q=kettle&session=abc&ref_id=1",
+			expected:  "https://www.example.com/search/?q=kettle",
+		},
+		{
+			name:      "Keep params prefixes match whole segments",
+			transform: example,
+			input:     "https://www.example.com/searches?session=abc&ref_id=1",
+			expected:  "https://www.example.com/searches/?session=abc",
+		},
+		{
+			name:      "Excluded paths are left alone",
+			transform: example,
+			input:     "https://www.example.com/raw/sr=1?ref_id=1",
+			expected:  "https://www.example.com/raw/sr=1?ref_id=1",
+		},
+		{
+			name:      "Exact host",
+			transform: example,
+			input:     "https://shop.example.net/a?ref_id=1",
+			expected:  "https://shop.example.net/a/",
+		},
+		{
+			name:      "Other hosts are left alone",
+			transform: example,
+			input:     "https://notexample.com/a?ref_id=1",
+			expected:  "https://notexample.com/a?ref_id=1",
+		},
+		{
+			name:      "Whole query and trailing slash",
+			transform: allParams,
+			input:     "https://blog.example.org/post/?id=1&utm_source=x",
+			expected:  "https://blog.example.org/post",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tc.transform.Apply(u)
+			if diff := cmp.Diff(tc.expected, u.String()); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSiteRulesErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "Unknown field", input: "sites:\n  - name: a\n    domains: [a.com]\n    param: [x]\n"},
+		{name: "Missing host match", input: "sites:\n  - name: a\n    params: [x]\n"},
+		{name: "Subdomain under domains", input: "sites:\n  - name: a\n    domains: [shop.a.com]\n"},
+		{name: "Public suffix under domains", input: "sites:\n  - name: a\n    domains: [co.uk]\n"},
+		{name: "Invalid name", input: "sites:\n  - name: My Site\n    domains: [a.com]\n"},
+		{name: "Invalid pattern", input: "sites:\n  - name: a\n    domains: [a.com]\n    param_patterns: ['(']\n"},
+		{name: "Keep params without params", input: "sites:\n  - name: a\n    domains: [a.com]\n    keep_params:\n      - path_prefixes: [/s]\n"},
+		{name: "Relative exclude prefix", input: "sites:\n  - name: a\n    domains: [a.com]\n    exclude_path_prefixes: [raw]\n"},
+		{name: "Relative keep params prefix", input: "sites:\n  - name: a\n    domains: [a.com]\n    keep_params:\n      - path_prefixes: [s]\n        params: [k]\n"},
+		{name: "Invalid trailing slash policy", input: "sites:\n  - name: a\n    domains: [a.com]\n    trailing_slash: remove\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseSiteRules([]byte(tc.input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestParseSiteRulesJSON(t *testing.T) {
+	rules, err := ParseSiteRules([]byte(`{"sites": [{"name": "a", "domains": ["a.com"], "params": ["x"]}]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []SiteRule{{Name: "a", HostMatcher: HostMatcher{Domains: []string{"a.com"}}, Params: []string{"x"}}}
+	if diff := cmp.Diff(expected, rules); diff != "" {
+		t.Errorf("Unexpected rules (-want +got):\n%s", diff)
+	}
+}
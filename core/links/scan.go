@@ -0,0 +1,141 @@
+package links
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"mvdan.cc/xurls/v2"
+)
+
+// urlMatcher finds the same URLs as xurls.Strict. The strict expression is a
+// case-insensitive alternation of several hundred schemes followed by a path
+// expression, and running it at every byte dominates processing time. The
+// matcher instead looks up the scheme in front of each colon in a set and only
+// runs the path expression, anchored, where a known scheme was found.
+type urlMatcher struct {
+	strict         *regexp.Regexp
+	path           *regexp.Regexp
+	schemes        map[string]bool
+	schemesNoAuth  map[string]bool
+	maxSchemeBytes int
+}
+
+var (
+	matcher     *urlMatcher
+	matcherOnce sync.Once
+)
+
+// strictURLMatcher returns the shared matcher, falling back to the plain
+// xurls expression if its layout is not the one the matcher was built for.
+func strictURLMatcher() *urlMatcher {
+	matcherOnce.Do(func() {
+		matcher = newURLMatcher()
+	})
+	return matcher
+}
+
+func newURLMatcher() *urlMatcher {
+	m := &urlMatcher{
+		strict:        xurls.Strict(),
+		schemes:       map[string]bool{},
+		schemesNoAuth: map[string]bool{},
+	}
+
+	schemePrefix := `(?:(?i)(?:` + anyOf(xurls.Schemes...) + `|` + anyOf(xurls.SchemesUnofficial...) + `)://|` +
+		anyOf(xurls.SchemesNoAuthority...) + `:)`
+	expr := m.strict.String()
+	if !strings.HasPrefix(expr, schemePrefix) {
+		return m
+	}
+	m.path = regexp.MustCompile(`^(?:` + strings.TrimPrefix(expr, schemePrefix) + `)`)
+
+	for _, scheme := range append(append([]string{}, xurls.Schemes...), xurls.SchemesUnofficial...) {
+		m.schemes[strings.ToLower(scheme)] = true
+		m.maxSchemeBytes = max(m.maxSchemeBytes, len(scheme))
+	}
+	for _, scheme := range xurls.SchemesNoAuthority {
+		m.schemesNoAuth[strings.ToLower(scheme)] = true
+		m.maxSchemeBytes = max(m.maxSchemeBytes, len(scheme))
+	}
+	return m
+}
+
+// findAll returns the index pairs of all URLs in token, which must not contain
+// whitespace. The result is identical to xurls.Strict().FindAllStringIndex.
+func (m *urlMatcher) findAll(token string) [][]int {
+	if m.path == nil || !isASCII(token) {
+		// case folding of non-ASCII runes is left to the regexp package
+		return m.strict.FindAllStringIndex(token, -1)
+	}
+
+	var locs [][]int
+	pos := 0
+	for pos < len(token) {
+		colon := strings.IndexByte(token[pos:], ':')
+		if colon < 0 {
+			break
+		}
+		colon += pos
+
+		next := colon + 1
+		for start := max(pos, colon-m.maxSchemeBytes); start < colon; start++ {
+			if end := m.matchAt(token, start, colon); end > 0 {
+				locs = append(locs, []int{start, end})
+				next = end
+				break
+			}
+		}
+		pos = next
+	}
+	return locs
+}
+
+// matchAt returns the end of the URL whose scheme spans token[start:colon],
+// or 0 if there is none. Schemes with an authority are preferred, as in the
+// strict expression.
+func (m *urlMatcher) matchAt(token string, start, colon int) int {
+	scheme := strings.ToLower(token[start:colon])
+	if m.schemes[scheme] && strings.HasPrefix(token[colon:], "://") {
+		if end := m.matchPath(token, colon+3); end > 0 {
+			return end
+		}
+	}
+	if m.schemesNoAuth[scheme] {
+		if end := m.matchPath(token, colon+1); end > 0 {
+			return end
+		}
+	}
+	return 0
+}
+
+func (m *urlMatcher) matchPath(token string, from int) int {
+	loc := m.path.FindStringIndex(token[from:])
+	if loc == nil || loc[1] == 0 {
+		return 0
+	}
+	return from + loc[1]
+}
+
+// anyOf mirrors the alternation xurls builds from its scheme lists
+func anyOf(strs ...string) string {
+	var b strings.Builder
+	b.WriteString("(?:")
+	for i, s := range strs {
+		if i != 0 {
+			b.WriteByte('|')
+		}
+		b.WriteString(regexp.QuoteMeta(s))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
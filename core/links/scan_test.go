@@ -0,0 +1,43 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"mvdan.cc/xurls/v2"
+)
+
+func TestURLMatcherMatchesStrict(t *testing.T) {
+	tokens := []string{
+		"https://example.com",
+		"(https://example.com/path?a=1&b=2#frag)",
+		"[text](https://example.com/a_(b))",
+		"xhttps://example.com",
+		"HTTPS://EXAMPLE.COM/",
+		"mailto:someone@example.com",
+		"file:///tmp/x",
+		"file:relative",
+		"a:b:https://example.com",
+		"https://a.com,https://b.com",
+		"https:",
+		"https://",
+		"notascheme://example.com",
+		"magnet:?xt=urn:btih:abc",
+		"<https://example.com>.",
+		"https://例子.测试/路径",
+		"http://x.com/'quoted'",
+		"nohost:",
+		":::",
+	}
+
+	m := newURLMatcher()
+	if m.path == nil {
+		t.Fatal("matcher fell back to the strict expression")
+	}
+	for _, token := range tokens {
+		want := xurls.Strict().FindAllStringIndex(token, -1)
+		if diff := cmp.Diff(want, m.findAll(token)); diff != "" {
+			t.Errorf("findAll(%q) mismatch (-want +got):\n%s", token, diff)
+		}
+	}
+}
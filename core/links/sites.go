@@ -0,0 +1,100 @@
+package links
+
+import "io"
+
+// The site transforms declared by the built-in site rules in sites.yaml
+var (
+	// Substack drops the whole query string from Substack URLs
+	Substack = defaultSite("substack")
+	// TheSweekly removes tracking parameters from TheSweekly URLs
+	TheSweekly = defaultSite("thesweekly")
+	// TechCrunch removes tracking parameters from TechCrunch URLs
+	TechCrunch = defaultSite("techcrunch")
+	// Facebook removes tracking parameters from Facebook URLs
+	Facebook = defaultSite("facebook")
+	// LinkedIn removes tracking parameters from LinkedIn URLs
+	LinkedIn = defaultSite("linkedin")
+	// WSJ removes tracking parameters from Wall Street Journal URLs
+	WSJ = defaultSite("wsj")
+	// Reddit removes tracking parameters from Reddit URLs and adds a trailing slash
+	Reddit = defaultSite("reddit")
+	// Shopify removes tracking parameters from Shopify store URLs
+	Shopify = defaultSite("shopify")
+	// Amazon removes tracking parameters and ref= path segments from Amazon URLs
+	Amazon = defaultSite("amazon")
+	// Bloomberg removes tracking parameters from Bloomberg URLs
+	Bloomberg = defaultSite("bloomberg")
+	// Netflix removes tracking parameters from Netflix URLs
+	Netflix = defaultSite("netflix")
+	// Instagram removes tracking parameters from Instagram URLs and adds a trailing slash
+	Instagram = defaultSite("instagram")
+	// TikTok removes tracking parameters from TikTok URLs
+	TikTok = defaultSite("tiktok")
+	// Walmart removes tracking parameters from Walmart URLs
+	Walmart = defaultSite("walmart")
+)
+
+func RemoveParamsFromSubstackURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Substack)(r, w)
+}
+
+func RemoveParamsFromTheSweeklyURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(TheSweekly)(r, w)
+}
+
+// RemoveParamsFromTechCrunchURLs removes tracking parameters from TechCrunch URLs
+func RemoveParamsFromTechCrunchURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(TechCrunch)(r, w)
+}
+
+// RemoveParamsFromFacebookURLs removes tracking parameters from Facebook URLs
+func RemoveParamsFromFacebookURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Facebook)(r, w)
+}
+
+// RemoveParamsFromLinkedInURLs removes tracking parameters from LinkedIn URLs
+func RemoveParamsFromLinkedInURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(LinkedIn)(r, w)
+}
+
+// RemoveParamsFromWSJURLs removes tracking parameters from Wall Street Journal URLs
+func RemoveParamsFromWSJURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(WSJ)(r, w)
+}
+
+// RemoveParamsFromRedditURLs removes tracking parameters from Reddit URLs
+func RemoveParamsFromRedditURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Reddit)(r, w)
+}
+
+// RemoveParamsFromShopifyURLs removes tracking parameters from Shopify URLs
+func RemoveParamsFromShopifyURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Shopify)(r, w)
+}
+
+// RemoveParamsFromAmazonURLs removes tracking parameters from Amazon URLs
+func RemoveParamsFromAmazonURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Amazon)(r, w)
+}
+
+// RemoveParamsFromBloombergURLs removes tracking parameters from Bloomberg URLs
+func RemoveParamsFromBloombergURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Bloomberg)(r, w)
+}
+
+// RemoveParamsFromNetflixURLs removes tracking parameters from Netflix URLs
+func RemoveParamsFromNetflixURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Netflix)(r, w)
+}
+
+func RemoveParamsFromInstagramURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Instagram)(r, w)
+}
+
+func RemoveParamsFromTikTokURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(TikTok)(r, w)
+}
+
+func RemoveParamsFromWalmartURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(Walmart)(r, w)
+}
@@ -0,0 +1,281 @@
+package links
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"go.yaml.in/yaml/v3"
+)
+
+// FindJSONURLs returns the string values of a JSON document that are URLs as
+// a whole, in document order. Object keys and strings that merely contain a
+// URL are left alone.
+func FindJSONURLs(content []byte) ([]URLSite, error) {
+	root, err := parseJSON(content)
+	if err != nil {
+		return nil, err
+	}
+
+	m := strictURLMatcher()
+	var sites []URLSite
+	var walk func(v *jsonValue)
+	walk = func(v *jsonValue) {
+		switch v.kind {
+		case '{':
+			for _, member := range v.members {
+				walk(member.value)
+			}
+		case '[':
+			for _, elem := range v.elems {
+				walk(elem)
+			}
+		case '"':
+			if m.isURL(v.str) {
+				sites = append(sites, URLSite{URL: string(content[v.start+1 : v.stop-1]), Offset: v.start + 1, Syntax: SyntaxEscapedString})
+			}
+		}
+	}
+	walk(root)
+
+	setLineColumns(content, sites)
+	return sites, nil
+}
+
+// FindYAMLURLs returns the string values of a YAML stream that are URLs as a
+// whole, in document order. Plain, single-quoted and double-quoted scalars
+// on a single line are found; mapping keys, block scalars and scalars that
+// span lines are left alone.
+func FindYAMLURLs(content []byte) ([]URLSite, error) {
+	var lineStarts []int
+	for start := 0; start <= len(content); start = endOfLine(content, start) + 1 {
+		lineStarts = append(lineStarts, start)
+	}
+
+	m := strictURLMatcher()
+	var sites []URLSite
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range n.Content {
+				walk(child)
+			}
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		case yaml.ScalarNode:
+			if n.Tag != "!!str" || !m.isURL(n.Value) || n.Line > len(lineStarts) {
+				return
+			}
+			// the parser counts columns in characters
+			start := lineStarts[n.Line-1]
+			line := content[start:endOfLine(content, start)]
+			for column := 1; column < n.Column && len(line) > 0; column++ {
+				_, size := utf8.DecodeRune(line)
+				start += size
+				line = line[size:]
+			}
+			if site, ok := yamlScalarSite(content, skipYAMLProperties(content, start), n); ok {
+				sites = append(sites, site)
+			}
+		}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		walk(&doc)
+	}
+
+	setLineColumns(content, sites)
+	return sites, nil
+}
+
+// skipYAMLProperties returns the offset of the scalar after any anchor and
+// tag at offset i
+func skipYAMLProperties(content []byte, i int) int {
+	for i < len(content) && (content[i] == '&' || content[i] == '!') {
+		for i < len(content) && !isYAMLSpace(content[i]) {
+			i++
+		}
+		for i < len(content) && (content[i] == ' ' || content[i] == '\t') {
+			i++
+		}
+	}
+	return i
+}
+
+func isYAMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// yamlScalarSite returns the site of the scalar n that starts at offset i.
+// The raw text must decode to the scalar's value, so that anything the
+// scanner above misjudges is left alone.
+func yamlScalarSite(content []byte, i int, n *yaml.Node) (URLSite, bool) {
+	switch n.Style &^ yaml.TaggedStyle {
+	case 0:
+		if bytes.HasPrefix(content[i:], []byte(n.Value)) {
+			return URLSite{URL: n.Value, Offset: i, Syntax: SyntaxVerbatimString}, true
+		}
+	case yaml.DoubleQuotedStyle:
+		stop := closingQuote(content, i, '"')
+		if stop > 0 && decodeJSONString(string(content[i+1:stop])) == n.Value {
+			return URLSite{URL: string(content[i+1 : stop]), Offset: i + 1, Syntax: SyntaxEscapedString}, true
+		}
+	case yaml.SingleQuotedStyle:
+		stop := closingQuote(content, i, '\'')
+		if stop > 0 && decodeSingleQuoted(string(content[i+1:stop])) == n.Value {
+			return URLSite{URL: string(content[i+1 : stop]), Offset: i + 1, Syntax: SyntaxSingleQuotedString}, true
+		}
+	}
+	return URLSite{}, false
+}
+
+// closingQuote returns the offset of the quote that closes the string opened
+// by the quote at offset i, or -1 when it is not closed on the same line. A
+// backslash escapes the next character in double-quoted strings; a doubled
+// quote is a quote in single-quoted ones.
+func closingQuote(content []byte, i int, quote byte) int {
+	if i >= len(content) || content[i] != quote {
+		return -1
+	}
+	for j := i + 1; j < len(content) && content[j] != '\n'; j++ {
+		switch {
+		case quote == '"' && content[j] == '\\':
+			j++
+		case content[j] != quote:
+		case quote == '\'' && j+1 < len(content) && content[j+1] == '\'':
+			j++
+		default:
+			return j
+		}
+	}
+	return -1
+}
+
+// decodeSingleQuoted returns the value of the raw text between the quotes of a
+// single-quoted YAML string
+func decodeSingleQuoted(raw string) string {
+	return strings.ReplaceAll(raw, "''", "'")
+}
+
+// FindTOMLURLs returns the string values of a TOML document that are URLs as
+// a whole, in document order. Basic and literal strings are found; keys and
+// multi-line strings are left alone.
+func FindTOMLURLs(content []byte) ([]URLSite, error) {
+	var document map[string]any
+	if err := toml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+
+	m := strictURLMatcher()
+	var sites []URLSite
+	var walk func(n *unstable.Node)
+	walk = func(n *unstable.Node) {
+		switch n.Kind {
+		case unstable.KeyValue:
+			walk(n.Value())
+		case unstable.Array, unstable.InlineTable:
+			for it := n.Children(); it.Next(); {
+				walk(it.Node())
+			}
+		case unstable.String:
+			value := string(n.Data)
+			if !m.isURL(value) {
+				return
+			}
+			start, stop := int(n.Raw.Offset), int(n.Raw.Offset+n.Raw.Length)
+			raw := string(content[start:stop])
+			switch {
+			case strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, `'''`):
+			case strings.HasPrefix(raw, `"`) && decodeJSONString(raw[1:len(raw)-1]) == value:
+				sites = append(sites, URLSite{URL: raw[1 : len(raw)-1], Offset: start + 1, Syntax: SyntaxEscapedString})
+			case strings.HasPrefix(raw, `'`) && raw[1:len(raw)-1] == value:
+				sites = append(sites, URLSite{URL: value, Offset: start + 1, Syntax: SyntaxVerbatimString})
+			}
+		}
+	}
+
+	var p unstable.Parser
+	p.Reset(content)
+	for p.NextExpression() {
+		walk(p.Expression())
+	}
+	if err := p.Error(); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+
+	setLineColumns(content, sites)
+	return sites, nil
+}
+
+// isURL reports whether s is a URL and nothing else
+func (m *urlMatcher) isURL(s string) bool {
+	locs := m.findAll(s)
+	return len(locs) == 1 && locs[0][0] == 0 && locs[0][1] == len(s)
+}
+
+// frontMatter is the metadata block at the start of a Markdown document,
+// YAML between --- lines or TOML between +++ lines
+type frontMatter struct {
+	block byteRange // from the opening line to the end of the closing line
+	body  byteRange // the YAML or TOML between them
+	find  func([]byte) ([]URLSite, error)
+}
+
+// findFrontMatter returns the front matter of a Markdown document, or nil
+func findFrontMatter(content []byte) *frontMatter {
+	opening := string(bytes.TrimRight(content[:endOfLine(content, 0)], " \t\r"))
+	fm := &frontMatter{}
+	closers := []string{opening}
+	switch opening {
+	case "---":
+		fm.find = FindYAMLURLs
+		closers = append(closers, "...")
+	case "+++":
+		fm.find = FindTOMLURLs
+	default:
+		return nil
+	}
+
+	fm.body.start = endOfLine(content, 0) + 1
+	for lineStart := fm.body.start; lineStart < len(content); {
+		lineStop := endOfLine(content, lineStart)
+		line := string(bytes.TrimRight(content[lineStart:lineStop], " \t\r"))
+		for _, closer := range closers {
+			if line == closer {
+				fm.body.stop = lineStart
+				fm.block = byteRange{0, min(lineStop+1, len(content))}
+				return fm
+			}
+		}
+		lineStart = lineStop + 1
+	}
+	return nil
+}
+
+// sites returns the URL sites of the front matter, as offsets into the whole
+// document. Front matter that does not parse has none.
+func (fm *frontMatter) sites(content []byte) []URLSite {
+	sites, err := fm.find(content[fm.body.start:fm.body.stop])
+	if err != nil {
+		return nil
+	}
+	for i := range sites {
+		sites[i].Offset += fm.body.start
+	}
+	return sites
+}
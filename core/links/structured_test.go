@@ -0,0 +1,187 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "String values",
+			input:    "{\n  \"url\": \"https://example.com/?utm_source=x&id=1\",\n  \"list\": [\"https://example.com/b?utm_source=y\", 1]\n}\n",
+			expected: "{\n  \"url\": \"https://example.com/?id=1\",\n  \"list\": [\"https://example.com/b\", 1]\n}\n",
+		},
+		{
+			name:     "Escapes are kept",
+			input:    `{"url": "https:\/\/example.com\/?id=1&utm_source=x"}`,
+			expected: `{"url": "https:\/\/example.com\/?id=1"}`,
+		},
+		{
+			name:     "Keys and strings that only contain a URL are left alone",
+			input:    `{"https://example.com/?utm_source=x": "see https://example.com/?utm_source=y"}`,
+			expected: `{"https://example.com/?utm_source=x": "see https://example.com/?utm_source=y"}`,
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), JSONURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := engine.RewriteURLs([]byte(`{"url": `), JSONURLs); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestRewriteYAML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Plain, single and double quoted scalars",
+			input:    "a: https://example.com/a?utm_source=x # comment\nb: 'https://example.com/b?utm_source=x'\nc: \"https://example.com/c?utm_source=x\"\n",
+			expected: "a: https://example.com/a # comment\nb: 'https://example.com/b'\nc: \"https://example.com/c\"\n",
+		},
+		{
+			name:     "Sequences, flow collections and several documents",
+			input:    "links:\n  - https://example.com/a?utm_source=x\n  - ['https://example.com/b?utm_source=x', 2]\n---\n{é: \"https://example.com/c?utm_source=x\"}\n",
+			expected: "links:\n  - https://example.com/a\n  - ['https://example.com/b', 2]\n---\n{é: \"https://example.com/c\"}\n",
+		},
+		{
+			name:     "Anchors and tags",
+			input:    "a: &first https://example.com/a?utm_source=x\nb: !!str 'https://example.com/b?utm_source=x'\nc: *first\n",
+			expected: "a: &first https://example.com/a\nb: !!str 'https://example.com/b'\nc: *first\n",
+		},
+		{
+			name:     "Keys, block scalars and prose are left alone",
+			input:    "https://example.com/?utm_source=x: 1\nb: |\n  https://example.com/?utm_source=y\nc: see https://example.com/?utm_source=z\n",
+			expected: "https://example.com/?utm_source=x: 1\nb: |\n  https://example.com/?utm_source=y\nc: see https://example.com/?utm_source=z\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), YAMLURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewriteTOML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Basic and literal strings",
+			input:    "# links\nhome = \"https://example.com/?utm_source=x\" # comment\n[site]\nrepo = 'https://example.com/r?utm_source=x'\n",
+			expected: "# links\nhome = \"https://example.com/\" # comment\n[site]\nrepo = 'https://example.com/r'\n",
+		},
+		{
+			name:     "Arrays and inline tables",
+			input:    "links = [\n  \"https://example.com/a?utm_source=x\",\n  { url = \"https://example.com/b?utm_source=x\" },\n]\n",
+			expected: "links = [\n  \"https://example.com/a\",\n  { url = \"https://example.com/b\" },\n]\n",
+		},
+		{
+			name:     "Keys and multi-line strings are left alone",
+			input:    "\"https://example.com/?utm_source=x\" = 1\nnote = \"\"\"https://example.com/?utm_source=y\"\"\"\n",
+			expected: "\"https://example.com/?utm_source=x\" = 1\nnote = \"\"\"https://example.com/?utm_source=y\"\"\"\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.RewriteURLs([]byte(tc.input), TOMLURLs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := engine.RewriteURLs([]byte("a = \n"), TOMLURLs); err == nil {
+		t.Error("Expected an error for invalid TOML")
+	}
+}
+
+func TestFindURLsFrontMatter(t *testing.T) {
+	content := "---\ntitle: https://a.example/\ntags: [x]\n---\nsee https://b.example/\n"
+
+	expected := []URLSite{
+		{URL: "https://a.example/", Offset: 11, Line: 2, Column: 8, Syntax: SyntaxVerbatimString},
+		{URL: "https://b.example/", Offset: 48, Line: 5, Column: 5, Syntax: SyntaxBare},
+	}
+
+	result := FindURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected sites (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewriteFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "YAML front matter",
+			input:    "---\n# source\nsource: \"https://example.com/?utm_source=x\"\nnote: see https://example.com/?utm_source=y\n---\n\nhttps://example.com/?utm_source=z\n",
+			expected: "---\n# source\nsource: \"https://example.com/\"\nnote: see https://example.com/?utm_source=y\n---\n\nhttps://example.com/\n",
+		},
+		{
+			name:     "TOML front matter",
+			input:    "+++\nsource = 'https://example.com/?utm_source=x'\n+++\n",
+			expected: "+++\nsource = 'https://example.com/'\n+++\n",
+		},
+		{
+			name:     "Invalid front matter is left alone",
+			input:    "---\nsource: [https://example.com/?utm_source=x\n---\n",
+			expected: "---\nsource: [https://example.com/?utm_source=x\n---\n",
+		},
+		{
+			name:     "A thematic break later on is not front matter",
+			input:    "text\n---\nhttps://example.com/?utm_source=x\n---\n",
+			expected: "text\n---\nhttps://example.com/\n---\n",
+		},
+	}
+
+	engine := NewEngine(GenericTracking)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := engine.Rewrite([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
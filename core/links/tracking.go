@@ -47,6 +47,9 @@ var CommonTrackingParams = []string{
 	"srsltid",
 }
 
+// GenericTracking removes common tracking parameters from the query and fragment of all URLs
+var GenericTracking = NewTransform("generic-tracking", "generic tracking parameter removal", anyHost, cleanGenericTracking)
+
 // isTrackingParam checks if a parameter should be removed (either UTM or in the common tracking list)
 func isTrackingParam(param string) bool {
 	return isUTMParam(param) || slices.Contains(CommonTrackingParams, param)
@@ -92,35 +95,25 @@ func buildFragmentFromParams(values url.Values) string {
 
 // RemoveGenericTrackingParams removes common tracking parameters from all URLs
 func RemoveGenericTrackingParams(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		q := u.Query()
-		changed := false
-		for param := range q {
-			if isTrackingParam(param) {
-				q.Del(param)
-				changed = true
-			}
-		}
-		if changed {
-			u.RawQuery = q.Encode()
-		}
+	return AsFunc(GenericTracking)(r, w)
+}
 
-		if u.Fragment != "" {
-			fragmentParams, err := parseFragmentParams(u.Fragment)
-			if err == nil && fragmentParams != nil {
-				fragmentChanged := false
-				for param := range fragmentParams {
-					if isTrackingParam(param) {
-						fragmentParams.Del(param)
-						fragmentChanged = true
-					}
-				}
-				if fragmentChanged {
-					u.Fragment = buildFragmentFromParams(fragmentParams)
+func cleanGenericTracking(u *url.URL) {
+	removeQueryParams(u, isTrackingParam)
+
+	if u.Fragment != "" {
+		fragmentParams, err := parseFragmentParams(u.Fragment)
+		if err == nil && fragmentParams != nil {
+			fragmentChanged := false
+			for param := range fragmentParams {
+				if isTrackingParam(param) {
+					fragmentParams.Del(param)
+					fragmentChanged = true
 				}
 			}
+			if fragmentChanged {
+				u.Fragment = buildFragmentFromParams(fragmentParams)
+			}
 		}
-
-		return u
-	})
+	}
 }
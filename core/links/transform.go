@@ -0,0 +1,113 @@
+package links
+
+import (
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Transform cleans individual URLs. Callers use MatchHost to skip URLs the
+// transform can never touch and Apply to rewrite the rest.
+type Transform interface {
+	// Name identifies the transform in config keys, flags and reports.
+	Name() string
+	// Description is a short summary such as "Google URL parameter removal".
+	Description() string
+	// MatchHost reports whether the transform applies to URLs on the
+	// lowercased host.
+	MatchHost(host string) bool
+	// Apply rewrites u in place and reports whether it changed.
+	Apply(u *url.URL) (changed bool)
+}
+
+type urlTransform struct {
+	name        string
+	description string
+	matchHost   func(host string) bool
+	clean       func(u *url.URL)
+}
+
+// NewTransform builds a Transform from a host matcher and a clean function
+// that mutates URLs on matching hosts. Apply reports a change whenever clean
+// alters the serialized URL.
+func NewTransform(name, description string, matchHost func(host string) bool, clean func(u *url.URL)) Transform {
+	return &urlTransform{
+		name:        name,
+		description: description,
+		matchHost:   matchHost,
+		clean:       clean,
+	}
+}
+
+func (t *urlTransform) Name() string {
+	return t.name
+}
+
+func (t *urlTransform) Description() string {
+	return t.description
+}
+
+func (t *urlTransform) MatchHost(host string) bool {
+	return t.matchHost(host)
+}
+
+func (t *urlTransform) Apply(u *url.URL) bool {
+	if !t.matchHost(hostOf(u)) {
+		return false
+	}
+	return t.applyMatched(u)
+}
+
+// applyMatched is Apply for URLs whose host is known to match
+func (t *urlTransform) applyMatched(u *url.URL) bool {
+	before := u.String()
+	t.clean(u)
+	return u.String() != before
+}
+
+// matchedApplier is implemented by transforms whose Apply checks the host
+// first, so that callers that have matched the host already can skip it
+type matchedApplier interface {
+	applyMatched(u *url.URL) bool
+}
+
+// applyMatched applies t to u, whose host t is known to match
+func applyMatched(t Transform, u *url.URL) bool {
+	if m, ok := t.(matchedApplier); ok {
+		return m.applyMatched(u)
+	}
+	return t.Apply(u)
+}
+
+// AsFunc adapts t to the func(io.Reader, io.Writer) error signature used by
+// core.ApplyTransforms. Every URL in the document is visited and URLs on
+// hosts t matches are passed to Apply.
+func AsFunc(t Transform) func(io.Reader, io.Writer) error {
+	return func(r io.Reader, w io.Writer) error {
+		return processURLs(r, w, func(u *url.URL) *url.URL {
+			if t.MatchHost(hostOf(u)) {
+				applyMatched(t, u)
+			}
+			return u
+		})
+	}
+}
+
+// Builtin returns the built-in URL transforms in the order they are applied.
+// The transforms of the built-in site rules run in the order sites.yaml lists
+// them.
+func Builtin() []Transform {
+	transforms := []Transform{GenericTracking, Google, YouTube}
+	transforms = append(transforms, builtinSites...)
+	return append(transforms, Conditional, TextFragments)
+}
+
+// hostOf returns the lowercased hostname of u without port
+func hostOf(u *url.URL) string {
+	return strings.ToLower(u.Hostname())
+}
+
+// anyHost matches every host, for transforms that are not site specific
+func anyHost(string) bool {
+	return true
+}
@@ -0,0 +1,102 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTransformApply(t *testing.T) {
+	testCases := []struct {
+		name            string
+		transform       Transform
+		input           string
+		expected        string
+		expectedChanged bool
+	}{
+		{
+			name:            "Matching host with tracking parameter",
+			transform:       Reddit,
+			input:           "https://www.reddit.com/r/golang/?share_id=abc",
+			expected:        "https://www.reddit.com/r/golang/",
+			expectedChanged: true,
+		},
+		{
+			name:            "Matching host already clean",
+			transform:       Reddit,
+			input:           "https://www.reddit.com/r/golang/",
+			expected:        "https://www.reddit.com/r/golang/",
+			expectedChanged: false,
+		},
+		{
+			name:            "Non-matching host is left alone",
+			transform:       Reddit,
+			input:           "https://example.com/r/golang?share_id=abc",
+			expected:        "https://example.com/r/golang?share_id=abc",
+			expectedChanged: false,
+		},
+		{
+			name:            "Host rewrite counts as a change",
+			transform:       YouTube,
+			input:           "https://www.youtube.com/watch?v=abc",
+			expected:        "https://youtu.be/abc",
+			expectedChanged: true,
+		},
+		{
+			name:            "Generic transform applies to any host",
+			transform:       TextFragments,
+			input:           "https://example.com/a#:~:text=hi",
+			expected:        "https://example.com/a",
+			expectedChanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Failed to parse URL: %v", err)
+			}
+			changed := tc.transform.Apply(u)
+			if changed != tc.expectedChanged {
+				t.Errorf("Apply() changed = %v, want %v", changed, tc.expectedChanged)
+			}
+			if u.String() != tc.expected {
+				t.Errorf("Apply() = %q, want %q", u.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestTransformMatchHost(t *testing.T) {
+	testCases := []struct {
+		transform Transform
+		host      string
+		expected  bool
+	}{
+		{Reddit, "old.reddit.com", true},
+		{Reddit, "redd.it", true},
+		{Reddit, "example.com", false},
+		{Amazon, "www.amazon.co.uk", true},
+		{Netflix, "netflix.com", true},
+		{GenericTracking, "anything.example", true},
+	}
+
+	for _, tc := range testCases {
+		if result := tc.transform.MatchHost(tc.host); result != tc.expected {
+			t.Errorf("%s.MatchHost(%q) = %v, want %v", tc.transform.Name(), tc.host, result, tc.expected)
+		}
+	}
+}
+
+func TestBuiltinNamesAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, transform := range Builtin() {
+		if transform.Name() == "" || transform.Description() == "" {
+			t.Errorf("Transform %q is missing a name or description", transform.Name())
+		}
+		if seen[transform.Name()] {
+			t.Errorf("Duplicate transform name %q", transform.Name())
+		}
+		seen[transform.Name()] = true
+	}
+}
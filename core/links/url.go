@@ -1,71 +1,215 @@
 package links
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
-
-	"mvdan.cc/xurls/v2"
+	"unicode"
 )
 
 var textFragmentRegex = regexp.MustCompile(`(?i)^:~:text=`)
 
-func isSubstackURL(u *url.URL) bool {
-	hostname := strings.ToLower(u.Hostname())
-	return hostname == "substack.com" || strings.HasSuffix(hostname, ".substack.com")
+// TextFragments removes #:~:text= fragments from all URLs
+var TextFragments = NewTransform("text-fragments", "text fragment removal", anyHost, cleanTextFragment)
+
+func RemoveTextFragmentsFromURLs(r io.Reader, w io.Writer) error {
+	return AsFunc(TextFragments)(r, w)
+}
+
+func cleanTextFragment(u *url.URL) {
+	if isTextFragment(u.Fragment) {
+		u.Fragment = ""
+	}
+}
+
+func isTextFragment(fragment string) bool {
+	return textFragmentRegex.MatchString(fragment)
 }
 
-func isTheSweeklyURL(u *url.URL) bool {
-	return strings.HasSuffix(strings.ToLower(u.Hostname()), "thesweekly.com")
+// URLSyntax is the construct of a document's markup a URL was found in.
+type URLSyntax int
+
+const (
+	SyntaxBare                URLSyntax = iota // a URL in prose
+	SyntaxInlineLink                           // the destination of [text](url) or ![alt](url)
+	SyntaxReferenceDefinition                  // the destination of [label]: url
+	SyntaxAutolink                             // <url>
+	SyntaxHTMLAttribute                        // an attribute such as href or src, with character references
+	SyntaxOrgLink                              // the target of an Org [[url][description]] link
+	SyntaxRSTLink                              // the target of a reStructuredText `text <url>`_ link
+	SyntaxAsciiDocLink                         // the target of an AsciiDoc url[text] or link:url[text] macro
+	SyntaxEscapedString                        // the text between the quotes of a JSON, double-quoted YAML or TOML basic string, with escapes
+	SyntaxSingleQuotedString                   // the text between the quotes of a single-quoted YAML string, with '' for a quote
+	SyntaxVerbatimString                       // a plain YAML scalar or the text between the quotes of a TOML literal string
+	SyntaxMarkupText                           // the text of an XML element or of HTML, with character references
+	SyntaxEscapedHTML                          // a URL in HTML that is itself escaped as the text of an XML element, with two levels of character references
+)
+
+// URLSite is a URL found in the prose of a Markdown document.
+type URLSite struct {
+	URL    string
+	Offset int // byte offset of the URL in the document
+	Line   int // 1-based line number
+	Column int // 1-based byte column within the line
+	Syntax URLSyntax
 }
 
-var theSweeklyParamsToRemove = []string{
-	"utm_source",
-	"publication_id",
-	"post_id",
-	"utm_campaign",
-	"isFreemail",
-	"r",
-	"triedRedirect",
-	"utm_medium",
+// FindURLs returns every URL in content that processURLs would rewrite, in
+// document order. Link destinations, reference definitions and autolinks are
+// found from the document structure, so their exact extent is known; other
+// URLs are found in the remaining prose. URLs in code blocks, code spans,
+// HTML blocks, inline HTML and HTML comments are skipped.
+func FindURLs(content []byte) []URLSite {
+	sites, _ := findURLSites(content)
+	return sites
 }
 
-func RemoveParamsFromSubstackURLs(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		if isSubstackURL(u) {
-			u.RawQuery = ""
-			u.Path = stripTrailingSlash(u.Path)
+// findURLSites returns the sites FindURLs does, along with the text range of
+// every inline link keyed by the offset of its destination URL.
+func findURLSites(content []byte) ([]URLSite, map[int]byteRange) {
+	m := strictURLMatcher()
+	doc := parseMarkdownDocument(content)
+
+	var structured []URLSite
+	linkText := make(map[int]byteRange)
+	addSite := func(start, stop int, syntax URLSyntax) {
+		if bytes.ContainsFunc(content[start:stop], unicode.IsSpace) {
+			// only possible between angle brackets, where it is left to
+			// the prose scan so that the spaces are not re-encoded
+			return
+		}
+		if locs := m.findAll(string(content[start:stop])); len(locs) == 0 || locs[0][0] != 0 {
+			// relative links and anything else that is not a URL
+			return
+		}
+		structured = append(structured, URLSite{URL: string(content[start:stop]), Offset: start, Syntax: syntax})
+	}
+	for _, link := range doc.links() {
+		if !link.reference {
+			addSite(link.urlStart, link.urlStop, SyntaxInlineLink)
+			if !link.image {
+				linkText[link.urlStart] = byteRange{link.textStart, link.textStop}
+			}
 		}
-		return u
+	}
+	for _, definition := range doc.definitions {
+		addSite(definition.destStart, definition.destStop, SyntaxReferenceDefinition)
+	}
+	for _, autolink := range doc.autolinks() {
+		addSite(autolink.start, autolink.stop, SyntaxAutolink)
+	}
+	for _, r := range doc.html {
+		structured = append(structured, findHTMLSites(content, r.start, r.stop)...)
+	}
+	if doc.frontMatter != nil {
+		structured = append(structured, doc.frontMatter.sites(content)...)
+	}
+	return withBareURLs(content, doc.skip, structured), linkText
+}
+
+// withBareURLs returns the structured sites, given without line and column,
+// together with the bare URLs in content that are neither part of one of them
+// nor in a skipped range, all in document order
+func withBareURLs(content []byte, skip []byteRange, structured []URLSite) []URLSite {
+	skip = slices.Clone(skip)
+	for _, site := range structured {
+		skip = append(skip, byteRange{site.Offset, site.Offset + len(site.URL)})
+	}
+	sort.Slice(skip, func(i, j int) bool {
+		return skip[i].start < skip[j].start
+	})
+
+	sites := findBareURLs(content, skip)
+	setLineColumns(content, structured)
+	sites = append(sites, structured...)
+	sort.SliceStable(sites, func(i, j int) bool {
+		return sites[i].Offset < sites[j].Offset
 	})
+	return sites
 }
 
-func RemoveParamsFromTheSweeklyURLs(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		if isTheSweeklyURL(u) {
-			q := u.Query()
-			for _, param := range theSweeklyParamsToRemove {
-				q.Del(param)
+// FindTextURLs returns every URL in plain text, in document order. Unlike
+// FindURLs, no Markdown structure is considered.
+func FindTextURLs(content []byte) []URLSite {
+	return findBareURLs(content, nil)
+}
+
+// findBareURLs returns the URLs in content outside of the sorted skip ranges
+func findBareURLs(content []byte, skip []byteRange) []URLSite {
+	m := strictURLMatcher()
+	var sites []URLSite
+	next := 0
+	offset := 0
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, loc := range m.findURLsInLine(line) {
+			start := offset + loc[0]
+			if overlaps(skip, &next, start, offset+loc[1]) {
+				continue
 			}
-			u.RawQuery = q.Encode()
+			sites = append(sites, URLSite{
+				URL:    line[loc[0]:loc[1]],
+				Offset: start,
+				Line:   i + 1,
+				Column: loc[0] + 1,
+			})
 		}
-		return u
-	})
+
+		offset += len(line) + 1
+	}
+	return sites
 }
 
-func RemoveTextFragmentsFromURLs(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		if isTextFragment(u.Fragment) {
-			u.Fragment = ""
+// setLineColumns fills in the line and column of sites from their offsets
+func setLineColumns(content []byte, sites []URLSite) {
+	lineStarts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
 		}
-		return u
-	})
+	}
+	for i := range sites {
+		line := sort.Search(len(lineStarts), func(l int) bool {
+			return lineStarts[l] > sites[i].Offset
+		})
+		sites[i].Line = line
+		sites[i].Column = sites[i].Offset - lineStarts[line-1] + 1
+	}
 }
 
-func isTextFragment(fragment string) bool {
-	return textFragmentRegex.MatchString(fragment)
+// findURLsInLine returns the index pairs of URLs in line. URLs never contain
+// whitespace and always contain a colon after the scheme, so only whitespace
+// separated tokens that contain a colon are searched.
+func (m *urlMatcher) findURLsInLine(line string) [][]int {
+	var locs [][]int
+	start := -1
+	for i := 0; i <= len(line); i++ {
+		if i < len(line) && !isURLSpace(line[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		token := line[start:i]
+		if strings.IndexByte(token, ':') >= 0 {
+			for _, loc := range m.findAll(token) {
+				locs = append(locs, []int{start + loc[0], start + loc[1]})
+			}
+		}
+		start = -1
+	}
+	return locs
+}
+
+func isURLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f'
 }
 
 func processURLs(r io.Reader, w io.Writer, processor func(*url.URL) *url.URL) error {
@@ -74,39 +218,44 @@ func processURLs(r io.Reader, w io.Writer, processor func(*url.URL) *url.URL) er
 		return fmt.Errorf("processURLs: failed to read input: %w", err)
 	}
 
-	codeBlockLevel := 0
-	lines := strings.Split(string(buf), "\n")
-	for i, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmedLine, "```") {
-			if codeBlockLevel == 0 {
-				codeBlockLevel++
-			} else {
-				codeBlockLevel--
-			}
-		}
-
-		if codeBlockLevel == 0 {
-			rxStrict := xurls.Strict()
-			lines[i] = rxStrict.ReplaceAllStringFunc(line, func(match string) string {
-				u, err := url.Parse(match)
-				if err != nil {
-					return match
-				}
+	var out strings.Builder
+	out.Grow(len(buf))
+	last := 0
+	for _, site := range FindURLs(buf) {
+		out.Write(buf[last:site.Offset])
+		out.WriteString(rewriteSite(site, processor))
+		last = site.Offset + len(site.URL)
+	}
+	out.Write(buf[last:])
 
-				u = processor(u)
+	_, err = io.WriteString(w, out.String())
+	if err != nil {
+		return fmt.Errorf("processURLs: failed to write output: %w", err)
+	}
 
-				u.RawQuery = strings.ReplaceAll(u.RawQuery, "%20", "+")
+	return nil
+}
 
-				return u.String()
-			})
-		}
+// rewriteSite rewrites the URL of site, decoding and re-encoding the escapes
+// of its syntax
+func rewriteSite(site URLSite, processor func(*url.URL) *url.URL) string {
+	decoded := decodedURL(site)
+	rewritten := rewriteURL(decoded, processor)
+	if rewritten == decoded {
+		return site.URL
 	}
+	return encodedURL(site, rewritten)
+}
 
-	_, err = w.Write([]byte(strings.Join(lines, "\n")))
+func rewriteURL(match string, processor func(*url.URL) *url.URL) string {
+	u, err := url.Parse(match)
 	if err != nil {
-		return fmt.Errorf("processURLs: failed to write output: %w", err)
+		return match
 	}
 
-	return nil
+	u = processor(u)
+
+	u.RawQuery = strings.ReplaceAll(u.RawQuery, "%20", "+")
+
+	return u.String()
 }
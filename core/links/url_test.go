@@ -127,3 +127,171 @@ And this one: https://example.com/article2#another-fragment
 		})
 	}
 }
+
+func TestFindURLs(t *testing.T) {
+	content := "Intro https://a.example/x\n" +
+		"```\n" +
+		"https://b.example/skipped\n" +
+		"```\n" +
+		"two https://c.example/1 and https://d.example/2"
+
+	expected := []URLSite{
+		{URL: "https://a.example/x", Offset: 6, Line: 1, Column: 7},
+		{URL: "https://c.example/1", Offset: 64, Line: 5, Column: 5},
+		{URL: "https://d.example/2", Offset: 88, Line: 5, Column: 29},
+	}
+
+	result := FindURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessURLsSkipsNonProse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Tilde fence",
+			input:    "~~~\nhttps://example.com/?utm_source=x\n~~~\nhttps://example.com/?utm_source=y\n",
+			expected: "~~~\nhttps://example.com/?utm_source=x\n~~~\nhttps://example.com/\n",
+		},
+		{
+			name: "Four backtick fence containing a three backtick fence",
+			input: "````md\n```\nhttps://example.com/?utm_source=a\n```\nhttps://example.com/?utm_source=b\n````\n" +
+				"https://example.com/?utm_source=c\n",
+			expected: "````md\n```\nhttps://example.com/?utm_source=a\n```\nhttps://example.com/?utm_source=b\n````\n" +
+				"https://example.com/\n",
+		},
+		{
+			name:     "Fence info string",
+			input:    "```sh https://example.com/?utm_source=x\necho\n```\n",
+			expected: "```sh https://example.com/?utm_source=x\necho\n```\n",
+		},
+		{
+			name:     "Indented code block",
+			input:    "Text\n\n    curl https://example.com/?utm_source=x\n\nhttps://example.com/?utm_source=y\n",
+			expected: "Text\n\n    curl https://example.com/?utm_source=x\n\nhttps://example.com/\n",
+		},
+		{
+			name:     "Indented list continuation is prose",
+			input:    "- item\n\n    https://example.com/?utm_source=x\n",
+			expected: "- item\n\n    https://example.com/\n",
+		},
+		{
+			name:     "Inline code span",
+			input:    "Run `curl https://example.com/?utm_source=x` or visit https://example.com/?utm_source=y\n",
+			expected: "Run `curl https://example.com/?utm_source=x` or visit https://example.com/\n",
+		},
+		{
+			name:     "Inline HTML comment",
+			input:    "See <!-- https://example.com/?utm_source=x --> https://example.com/?utm_source=y\n",
+			expected: "See <!-- https://example.com/?utm_source=x --> https://example.com/\n",
+		},
+		{
+			name:     "HTML comment block",
+			input:    "<!--\nhttps://example.com/?utm_source=x\n-->\n\nhttps://example.com/?utm_source=y\n",
+			expected: "<!--\nhttps://example.com/?utm_source=x\n-->\n\nhttps://example.com/\n",
+		},
+		{
+			name:     "Fence inside a block quote",
+			input:    "> ```\n> https://example.com/?utm_source=x\n> ```\n> https://example.com/?utm_source=y\n",
+			expected: "> ```\n> https://example.com/?utm_source=x\n> ```\n> https://example.com/\n",
+		},
+		{
+			name:     "Link destinations and autolinks",
+			input:    "[a](https://example.com/?utm_source=x) <https://example.com/b?utm_source=y>\n",
+			expected: "[a](https://example.com/) <https://example.com/b>\n",
+		},
+		{
+			name:     "Other bytes are preserved exactly",
+			input:    "Title\r\n=====\r\n\r\n*\temph*  https://example.com/?utm_source=x\t\r\n",
+			expected: "Title\r\n=====\r\n\r\n*\temph*  https://example.com/\t\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			err := RemoveGenericTrackingParams(strings.NewReader(tc.input), &output)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, output.String()); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindURLsSyntax(t *testing.T) {
+	content := "See [docs](https://a.example/x. \"Docs\") and <https://b.example/y>.\n" +
+		"![logo](https://c.example/l.png) https://d.example/z\n" +
+		"\n" +
+		"[ref]: <https://e.example/r> 'Ref'\n" +
+		"[rel]: ./relative.md\n"
+
+	expected := []URLSite{
+		{URL: "https://a.example/x.", Offset: 11, Line: 1, Column: 12, Syntax: SyntaxInlineLink},
+		{URL: "https://b.example/y", Offset: 45, Line: 1, Column: 46, Syntax: SyntaxAutolink},
+		{URL: "https://c.example/l.png", Offset: 75, Line: 2, Column: 9, Syntax: SyntaxInlineLink},
+		{URL: "https://d.example/z", Offset: 100, Line: 2, Column: 34},
+		{URL: "https://e.example/r", Offset: 129, Line: 4, Column: 9, Syntax: SyntaxReferenceDefinition},
+	}
+
+	result := FindURLs([]byte(content))
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessURLsStructuredSites(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Reference definition with title",
+			input:    "[id]: https://example.com/a?utm_source=x \"Title\"\n",
+			expected: "[id]: https://example.com/a \"Title\"\n",
+		},
+		{
+			name:     "Reference definition with angle brackets and title on the next line",
+			input:    "Text\n\n[id]:\n  <https://example.com/a?utm_source=x>\n  'Title'\n",
+			expected: "Text\n\n[id]:\n  <https://example.com/a>\n  'Title'\n",
+		},
+		{
+			name:     "Autolink",
+			input:    "<https://example.com/a?utm_source=x>\n",
+			expected: "<https://example.com/a>\n",
+		},
+		{
+			name:     "Inline link destination ending in punctuation",
+			input:    "[a](https://example.com/a.?utm_source=x)\n",
+			expected: "[a](https://example.com/a.)\n",
+		},
+		{
+			name:     "Definition inside a paragraph is prose",
+			input:    "Text\n[id]: https://example.com/a?utm_source=x\n",
+			expected: "Text\n[id]: https://example.com/a\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			err := RemoveGenericTrackingParams(strings.NewReader(tc.input), &output)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, output.String()); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
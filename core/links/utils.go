@@ -1,6 +1,7 @@
 package links
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -26,3 +27,48 @@ func stripTrailingSlash(path string) string {
 	}
 	return strings.TrimRight(path, "/")
 }
+
+// removeQueryParams deletes every query parameter for which shouldRemove
+// returns true. The query is only re-encoded when something was removed.
+func removeQueryParams(u *url.URL, shouldRemove func(param string) bool) {
+	q := u.Query()
+	changed := false
+	for param := range q {
+		if shouldRemove(param) {
+			q.Del(param)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+}
+
+// transformName turns a name such as "amazon search" into one fit for
+// config keys and flags
+func transformName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// isSpaceByte reports whether c is a space, tab or line ending
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isWordByte reports whether c is an ASCII letter, digit or underscore
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
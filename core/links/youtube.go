@@ -18,6 +18,12 @@ var YouTubeParamsToRemove = []string{
 	"rs",
 }
 
+// youtubeWatchParams are the only parameters kept on watch pages
+var youtubeWatchParams = KeepParams{
+	PathPrefixes: []string{"/watch"},
+	Params:       []string{"index", "list", "t", "v"},
+}
+
 var youtubeCountRegex *regexp.Regexp
 
 func init() {
@@ -40,69 +46,73 @@ func buildYouTubeCountRegex() (*regexp.Regexp, error) {
 	return regexp.Compile(pattern)
 }
 
+// YouTube removes tracking parameters from YouTube URLs and shortens them to youtu.be
+var YouTube = NewTransform("youtube", "YouTube URL parameter removal", isYouTubeHost, cleanYouTubeURL)
+
 // isYouTubeURL checks if a URL is from YouTube
 func isYouTubeURL(u *url.URL) bool {
-	youTubeDomains := []string{
-		"youtube.com",
-		"youtu.be",
-		"ytimg.com",
-	}
-	for _, domain := range youTubeDomains {
-		if strings.Contains(strings.ToLower(u.Hostname()), domain) {
-			return true
-		}
-	}
-	return false
+	return isYouTubeHost(hostOf(u))
+}
+
+// youtubeHosts are the hosts of YouTube pages, short links and thumbnails
+var youtubeHosts = HostMatcher{Domains: []string{"youtube.com", "youtu.be", "ytimg.com"}}
+
+// youtubePageHosts are the hosts of YouTube pages, which have short links
+var youtubePageHosts = HostMatcher{Domains: []string{"youtube.com"}}
+
+// isYouTubeHost checks if a host belongs to YouTube
+func isYouTubeHost(hostname string) bool {
+	return youtubeHosts.Match(hostname)
 }
 
 // RemoveParamsFromYouTubeURLs removes tracking parameters from YouTube URLs
 func RemoveParamsFromYouTubeURLs(r io.Reader, w io.Writer) error {
-	return processURLs(r, w, func(u *url.URL) *url.URL {
-		if !isYouTubeURL(u) {
-			return u
-		}
+	return AsFunc(YouTube)(r, w)
+}
 
-		q := u.Query()
-		changed := false
+func cleanYouTubeURL(u *url.URL) {
+	if youtubeWatchParams.matchesPath(u.Path) {
+		removeQueryParams(u, youtubeWatchParams.removes)
+	}
+
+	q := u.Query()
+	changed := false
 
-		for _, param := range YouTubeParamsToRemove {
-			if q.Has(param) {
-				q.Del(param)
-				changed = true
-			}
+	for _, param := range YouTubeParamsToRemove {
+		if q.Has(param) {
+			q.Del(param)
+			changed = true
 		}
+	}
 
+	if youtubePageHosts.Match(hostOf(u)) {
+		switch {
 		// Convert youtube.com/watch?v=X to youtu.be/X
-		if strings.Contains(strings.ToLower(u.Hostname()), "youtube.com") && u.Path == "/watch" && q.Has("v") {
+		case u.Path == "/watch" && q.Has("v"):
 			videoID := q.Get("v")
 			q.Del("v")
 			u.Host = "youtu.be"
 			u.Path = "/" + videoID
 			changed = true
-		}
 
 		// Convert youtube.com/shorts/VIDEO_ID to youtu.be/VIDEO_ID
-		if strings.Contains(strings.ToLower(u.Hostname()), "youtube.com") && strings.HasPrefix(u.Path, "/shorts/") {
-			videoID := strings.TrimPrefix(u.Path, "/shorts/")
+		case strings.HasPrefix(u.Path, "/shorts/"):
 			u.Host = "youtu.be"
-			u.Path = "/" + videoID
+			u.Path = "/" + strings.TrimPrefix(u.Path, "/shorts/")
 			changed = true
-		}
 
 		// Convert youtube.com/live/VIDEO_ID to youtu.be/VIDEO_ID
-		if strings.Contains(strings.ToLower(u.Hostname()), "youtube.com") && strings.HasPrefix(u.Path, "/live/") {
-			videoID := strings.TrimPrefix(u.Path, "/live/")
+		case strings.HasPrefix(u.Path, "/live/"):
 			u.Host = "youtu.be"
-			u.Path = "/" + videoID
+			u.Path = "/" + strings.TrimPrefix(u.Path, "/live/")
 			changed = true
 		}
+	}
 
-		if changed {
-			u.RawQuery = q.Encode()
-		}
-		u.Path = stripTrailingSlash(u.Path)
-		return u
-	})
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	u.Path = stripTrailingSlash(u.Path)
 }
 
 // RemoveYouTubeCountFromMarkdownLinks removes view counts from YouTube markdown links
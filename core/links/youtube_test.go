@@ -35,6 +35,11 @@ func TestRemoveParamsFromYouTubeURLs(t *testing.T) {
 			input:    "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
 			expected: "https://youtu.be/dQw4w9WgXcQ",
 		},
+		{
+			name:     "YouTube watch URL keeps only known parameters",
+			input:    "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42&pp=ygUIcmlja3JvbGw%3D&ab_channel=RickAstley",
+			expected: "https://youtu.be/dQw4w9WgXcQ?t=42",
+		},
 		{
 			name:     "YouTube link with mixed parameters",
 			input:    "https://www.youtube.com/watch?v=dQw4w9WgXcQ&si=test123&list=PLx2ksyallYzW4WNYHD9xOFrPRYGlntAft&app=mobile",
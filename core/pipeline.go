@@ -0,0 +1,311 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/gkwa/littlewill/file"
+)
+
+// maxPipelinePasses bounds how often the pipeline runs over a document before
+// giving up on reaching a stable result.
+const maxPipelinePasses = 10
+
+// Pipeline is the ordered set of transforms applied to a document. URL
+// transforms run first, in a single pass over every URL in the document,
+// followed by the document transforms in order. The whole pipeline is repeated
+// until the document no longer changes, so applying it twice gives the same
+// result as applying it once.
+type Pipeline struct {
+	Engine   *links.Engine
+	Document []NamedTransform
+	Org      []NamedTransform // the document transforms for Org documents
+
+	// MergeDuplicateBookmarks removes bookmarks that become duplicates of
+	// earlier ones once their URLs are cleaned.
+	MergeDuplicateBookmarks bool
+}
+
+// NewPipeline builds a Pipeline from URL transforms and document transforms.
+func NewPipeline(urlTransforms []links.Transform, document ...NamedTransform) Pipeline {
+	return Pipeline{
+		Engine:   links.NewEngine(urlTransforms...),
+		Document: document,
+	}
+}
+
+// Apply returns the transformed content and the URLs rewritten by the URL
+// transforms. Transforms that keep undoing each other's changes are reported
+// as an error naming them.
+func (p Pipeline) Apply(content []byte) ([]byte, []URLChange, error) {
+	return p.run(content, p.applyOnce)
+}
+
+// ApplyOrg returns an Org document with its URLs cleaned, followed by the Org
+// document transforms, and the URLs rewritten.
+func (p Pipeline) ApplyOrg(content []byte) ([]byte, []URLChange, error) {
+	return p.run(content, p.applyOrgOnce)
+}
+
+// ApplyURLs returns content with the URLs find finds in it cleaned, and the
+// URLs rewritten. It serves the formats that have no document transforms.
+func (p Pipeline) ApplyURLs(content []byte, find links.URLFinder) ([]byte, []URLChange, error) {
+	return p.run(content, func(content []byte) ([]byte, []URLChange, []string, error) {
+		if p.Engine == nil {
+			return content, nil, nil, nil
+		}
+		return urlPass(p.Engine.RewriteURLs(content, find))
+	})
+}
+
+// ApplyBookmarks returns a bookmark export with every bookmark URL cleaned and
+// everything else left as it was, the URLs rewritten and the bookmarks that
+// became duplicates of earlier ones once cleaned. Duplicates are removed when
+// MergeDuplicateBookmarks is set.
+func (p Pipeline) ApplyBookmarks(format links.BookmarkFormat, content []byte) ([]byte, []URLChange, []links.BookmarkDuplicate, error) {
+	var duplicates []links.BookmarkDuplicate
+	first := true
+	result, changes, err := p.run(content, func(content []byte) ([]byte, []URLChange, []string, error) {
+		if p.Engine == nil {
+			return content, nil, nil, nil
+		}
+		rewritten, urlChanges, found, err := p.Engine.RewriteBookmarks(format, content, p.MergeDuplicateBookmarks)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to rewrite bookmarks: %w", err)
+		}
+		// later passes see cleaned URLs, so only the first finds duplicates
+		if first {
+			duplicates = found
+			first = false
+		}
+		changes, changedBy := convertChanges(urlChanges)
+		if p.MergeDuplicateBookmarks && len(found) > 0 {
+			changedBy = append(changedBy, "merge-duplicate-bookmarks")
+		}
+		return rewritten, changes, changedBy, nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return result, changes, duplicates, nil
+}
+
+// ApplyFile applies the pipeline in the mode suited to the kind of content
+// detected from path and content. Content of kinds that are not cleaned is
+// returned as is.
+func (p Pipeline) ApplyFile(path string, content []byte) ([]byte, []URLChange, error) {
+	process := p.processor(file.DetectContent(path, content))
+	if process == nil {
+		return content, nil, nil
+	}
+	return process(content)
+}
+
+// processor returns the mode that cleans content of kind, or nil when such
+// content is not cleaned.
+func (p Pipeline) processor(kind file.Kind) func([]byte) ([]byte, []URLChange, error) {
+	if format, ok := BookmarkFormat(kind); ok {
+		return p.bookmarkProcessor(format)
+	}
+	if find, ok := urlFinders[kind]; ok {
+		return func(content []byte) ([]byte, []URLChange, error) {
+			return p.ApplyURLs(content, find)
+		}
+	}
+	switch kind {
+	case file.Markdown, file.Text:
+		// plain text may hold code fences and inline code like Markdown
+		return p.Apply
+	case file.Org:
+		return p.ApplyOrg
+	default:
+		return nil
+	}
+}
+
+// urlFinders find the URLs in the kinds of content that only have their URLs
+// cleaned
+var urlFinders = map[file.Kind]links.URLFinder{
+	file.HTML:     links.HTMLURLs,
+	file.RST:      links.RSTURLs,
+	file.AsciiDoc: links.AsciiDocURLs,
+	file.JSON:     links.JSONURLs,
+	file.YAML:     links.YAMLURLs,
+	file.TOML:     links.TOMLURLs,
+	file.RSS:      links.FeedURLs,
+	file.Atom:     links.FeedURLs,
+	file.OPML:     links.FeedURLs,
+}
+
+// bookmarkProcessor returns the mode that cleans bookmark exports of format
+func (p Pipeline) bookmarkProcessor(format links.BookmarkFormat) func([]byte) ([]byte, []URLChange, error) {
+	return func(content []byte) ([]byte, []URLChange, error) {
+		result, changes, _, err := p.ApplyBookmarks(format, content)
+		return result, changes, err
+	}
+}
+
+// BookmarkFormat returns the bookmark export format of content of kind, and
+// whether it is a bookmark export at all.
+func BookmarkFormat(kind file.Kind) (links.BookmarkFormat, bool) {
+	switch kind {
+	case file.NetscapeBookmarks:
+		return links.NetscapeBookmarks, true
+	case file.ChromeBookmarks:
+		return links.ChromeBookmarks, true
+	case file.FirefoxBookmarks:
+		return links.FirefoxBookmarks, true
+	default:
+		return 0, false
+	}
+}
+
+// run repeats once until the content no longer changes
+func (p Pipeline) run(content []byte, once func([]byte) ([]byte, []URLChange, []string, error)) ([]byte, []URLChange, error) {
+	var changes []URLChange
+	var history [][]string // names of the stages that changed each pass
+	seen := map[[sha256.Size]byte]int{sha256.Sum256(content): 0}
+
+	for pass := 0; pass < maxPipelinePasses; pass++ {
+		next, passChanges, changedBy, err := once(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changedBy) == 0 {
+			return content, changes, nil
+		}
+		changes = mergeURLChanges(changes, passChanges)
+		history = append(history, changedBy)
+
+		sum := sha256.Sum256(next)
+		if first, ok := seen[sum]; ok {
+			var names []string
+			for _, stages := range history[first:] {
+				for _, name := range stages {
+					if !slices.Contains(names, name) {
+						names = append(names, name)
+					}
+				}
+			}
+			return nil, nil, fmt.Errorf("transforms %s keep undoing each other's changes", strings.Join(names, " and "))
+		}
+		seen[sum] = len(history)
+		content = next
+	}
+
+	return nil, nil, fmt.Errorf("content did not stabilize after %d passes", maxPipelinePasses)
+}
+
+// applyOnce runs every stage of the pipeline once and returns the names of the
+// stages that changed the content.
+func (p Pipeline) applyOnce(content []byte) ([]byte, []URLChange, []string, error) {
+	var rewrite func([]byte) ([]byte, []links.Change, error)
+	if p.Engine != nil {
+		rewrite = p.Engine.Rewrite
+	}
+	return applyStages(content, rewrite, p.Document)
+}
+
+// applyOrgOnce runs the URL pass and the Org document transforms once
+func (p Pipeline) applyOrgOnce(content []byte) ([]byte, []URLChange, []string, error) {
+	var rewrite func([]byte) ([]byte, []links.Change, error)
+	if p.Engine != nil {
+		rewrite = func(content []byte) ([]byte, []links.Change, error) {
+			return p.Engine.RewriteURLs(content, links.OrgURLs)
+		}
+	}
+	return applyStages(content, rewrite, p.Org)
+}
+
+// applyStages runs the URL rewrite, if any, followed by the document
+// transforms and returns the names of the stages that changed the content.
+func applyStages(content []byte, rewrite func([]byte) ([]byte, []links.Change, error), document []NamedTransform) ([]byte, []URLChange, []string, error) {
+	var changes []URLChange
+	var changedBy []string
+
+	if rewrite != nil {
+		rewritten, urlChanges, err := rewrite(content)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to rewrite URLs: %w", err)
+		}
+		changes, changedBy = convertChanges(urlChanges)
+		content = rewritten
+	}
+
+	for _, transform := range document {
+		processed, err := ApplyTransforms(content, transform.Func)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to apply %s: %w", transform.Name, err)
+		}
+		if !bytes.Equal(processed, content) {
+			changedBy = append(changedBy, transform.Name)
+		}
+		content = processed
+	}
+
+	return content, changes, changedBy, nil
+}
+
+// urlPass converts the result of an engine rewrite for run
+func urlPass(rewritten []byte, urlChanges []links.Change, err error) ([]byte, []URLChange, []string, error) {
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to rewrite URLs: %w", err)
+	}
+	changes, changedBy := convertChanges(urlChanges)
+	return rewritten, changes, changedBy, nil
+}
+
+// convertChanges returns the engine's changes as URLChanges along with the
+// names of the transforms responsible
+func convertChanges(urlChanges []links.Change) ([]URLChange, []string) {
+	var changes []URLChange
+	var changedBy []string
+	for _, change := range urlChanges {
+		changes = append(changes, URLChange{
+			Original:   change.Site.URL,
+			Cleaned:    change.Cleaned,
+			Line:       change.Site.Line,
+			Column:     change.Site.Column,
+			Transforms: change.Transforms,
+		})
+		for _, name := range change.Transforms {
+			if !slices.Contains(changedBy, name) {
+				changedBy = append(changedBy, name)
+			}
+		}
+	}
+	if len(urlChanges) > 0 && len(changedBy) == 0 {
+		// re-encoded URLs without a transform to name
+		changedBy = append(changedBy, "url-normalization")
+	}
+	return changes, changedBy
+}
+
+// mergeURLChanges folds the changes of a later pass into those of earlier
+// passes, so that a URL rewritten twice is reported once from its original
+// to its final form.
+func mergeURLChanges(changes, later []URLChange) []URLChange {
+	for _, change := range later {
+		i := slices.IndexFunc(changes, func(c URLChange) bool {
+			return c.Cleaned == change.Original && c.Line == change.Line
+		})
+		if i < 0 {
+			changes = append(changes, change)
+			continue
+		}
+		changes[i].Cleaned = change.Cleaned
+		for _, name := range change.Transforms {
+			if !slices.Contains(changes[i].Transforms, name) {
+				changes[i].Transforms = append(changes[i].Transforms, name)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(changes, func(c URLChange) bool {
+		return c.Original == c.Cleaned
+	})
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPipelineApply(t *testing.T) {
+	pipeline := NewPipeline(
+		[]links.Transform{links.GenericTracking, links.YouTube},
+		NamedTransform{Name: "youtube-count", Func: links.RemoveYouTubeCountFromMarkdownLinks},
+	)
+
+	content := "# Notes\n" +
+		"Clean: https://example.com/a\n" +
+		"Video: [(12) Talk](https://www.youtube.com/watch?v=abc&si=x&utm_source=y)\n" +
+		"```\n" +
+		"https://example.com/b?utm_source=code\n" +
+		"```\n" +
+		"  See https://example.com/c?fbclid=1\n"
+
+	expectedContent := "# Notes\n" +
+		"Clean: https://example.com/a\n" +
+		"Video: [Talk](https://youtu.be/abc)\n" +
+		"```\n" +
+		"https://example.com/b?utm_source=code\n" +
+		"```\n" +
+		"  See https://example.com/c\n"
+
+	result, changes, err := pipeline.Apply([]byte(content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expectedContent, string(result)); diff != "" {
+		t.Errorf("Unexpected content (-want +got):\n%s", diff)
+	}
+
+	expected := []URLChange{
+		{
+			Original:   "https://www.youtube.com/watch?v=abc&si=x&utm_source=y",
+			Cleaned:    "https://youtu.be/abc",
+			Line:       3,
+			Column:     20,
+			Transforms: []string{"generic-tracking", "youtube"},
+		},
+		{
+			Original:   "https://example.com/c?fbclid=1",
+			Cleaned:    "https://example.com/c",
+			Line:       7,
+			Column:     7,
+			Transforms: []string{"generic-tracking"},
+		},
+	}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestPipelineApplyIsIdempotent(t *testing.T) {
+	pipeline := NewPipeline(
+		links.Builtin(),
+		NamedTransform{Name: "youtube-count", Func: links.RemoveYouTubeCountFromMarkdownLinks},
+	)
+
+	content := "# Reading\n" +
+		"- [(3) Talk](https://www.youtube.com/watch?v=abc&t=10s&si=x)\n" +
+		"- https://www.reddit.com/r/golang/comments/abc/title?utm_source=share\n" +
+		"- https://www.instagram.com/p/xyz?igsh=1\n" +
+		"- https://www.amazon.com/Some-Book/dp/B000000000/ref=sr_1_1?keywords=go\n" +
+		"- https://www.wsj.com/articles/story/?mod=hp_lead_pos1\n" +
+		"- https://example.substack.com/p/post/?utm_source=x\n" +
+		"- https://example.com/page?utm_medium=email#:~:text=quote\n" +
+		"- https://www.google.com/search?q=go+fixpoint&hl=en&sxsrf=abc\n"
+
+	once, _, err := pipeline.Apply([]byte(content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	twice, changes, err := pipeline.Apply(once)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(string(once), string(twice)); diff != "" {
+		t.Errorf("Second run changed the document (-first +second):\n%s", diff)
+	}
+	if changes != nil {
+		t.Errorf("Second run reported changes: %v", changes)
+	}
+}
+
+func TestPipelineApplyReachesFixpoint(t *testing.T) {
+	// the document transform introduces a URL that the URL pass then cleans
+	pipeline := NewPipeline(
+		[]links.Transform{links.GenericTracking},
+		NamedTransform{Name: "expand", Func: replacer("see-home", "https://example.com/?utm_source=x")},
+	)
+
+	result, changes, err := pipeline.Apply([]byte("Go to see-home now\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("Go to https://example.com/ now\n", string(result)); diff != "" {
+		t.Errorf("Unexpected content (-want +got):\n%s", diff)
+	}
+
+	expected := []URLChange{
+		{
+			Original:   "https://example.com/?utm_source=x",
+			Cleaned:    "https://example.com/",
+			Line:       1,
+			Column:     7,
+			Transforms: []string{"generic-tracking"},
+		},
+	}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("Unexpected changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestPipelineApplyOscillation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		pipeline      Pipeline
+		expectedNames string
+	}{
+		{
+			name: "Document transforms undoing each other",
+			pipeline: NewPipeline(nil,
+				NamedTransform{Name: "to-b", Func: replacer("a", "b")},
+				NamedTransform{Name: "to-a", Func: replacer("b", "a")},
+			),
+			expectedNames: "to-b and to-a",
+		},
+		{
+			name: "URL transforms undoing each other",
+			pipeline: NewPipeline([]links.Transform{
+				links.NewTransform("add-slash", "trailing slash addition", isExampleHost, func(u *url.URL) {
+					u.Path += "/"
+				}),
+				links.NewTransform("strip-slash", "trailing slash removal", isExampleHost, func(u *url.URL) {
+					u.Path = strings.TrimSuffix(u.Path, "/")
+				}),
+			}),
+			expectedNames: "add-slash and strip-slash",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tc.pipeline.Apply([]byte("a https://example.com/a\n"))
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expectedNames) {
+				t.Errorf("Error %q does not contain %q", err, tc.expectedNames)
+			}
+		})
+	}
+}
+
+func isExampleHost(host string) bool {
+	return host == "example.com"
+}
+
+func replacer(old, new string) func(io.Reader, io.Writer) error {
+	return func(r io.Reader, w io.Writer) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, strings.ReplaceAll(string(content), old, new))
+		return err
+	}
+}
+
+func TestPipelineApplyFile(t *testing.T) {
+	pipeline := NewPipeline(
+		[]links.Transform{links.GenericTracking},
+		NamedTransform{Name: "markdown-link-whitespace", Func: links.RemoveWhitespaceFromMarkdownLinks},
+	)
+	pipeline.Org = []NamedTransform{{Name: "markdown-link-whitespace", Func: links.RemoveWhitespaceFromOrgLinks}}
+
+	testCases := []struct {
+		name     string
+		path     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "HTML file is not cleaned as Markdown",
+			path:     "page.HTML",
+			input:    `<p>[  text  ](https://example.com/?utm_source=x) <a href="https://example.com/?utm_source=y&amp;id=1">a</a></p>`,
+			expected: `<p>[  text  ](https://example.com/) <a href="https://example.com/?id=1">a</a></p>`,
+		},
+		{
+			name:     "Markdown file",
+			path:     "notes.md",
+			input:    "[  text  ](https://example.com/?utm_source=x)\n",
+			expected: "[text](https://example.com/)\n",
+		},
+		{
+			name:     "Org file",
+			path:     "notes.org",
+			input:    "[[https://example.com/?utm_source=x][  text  ]]\n#+BEGIN_SRC\nhttps://example.com/?utm_source=y\n#+END_SRC\n",
+			expected: "[[https://example.com/][text]]\n#+BEGIN_SRC\nhttps://example.com/?utm_source=y\n#+END_SRC\n",
+		},
+		{
+			name:     "reStructuredText file",
+			path:     "index.rst",
+			input:    "`text <https://example.com/?utm_source=x>`_\n\n.. code:: sh\n\n   https://example.com/?utm_source=y\n",
+			expected: "`text <https://example.com/>`_\n\n.. code:: sh\n\n   https://example.com/?utm_source=y\n",
+		},
+		{
+			name:     "AsciiDoc file",
+			path:     "guide.adoc",
+			input:    "https://example.com/?utm_source=x[text]\n----\nhttps://example.com/?utm_source=y\n----\n",
+			expected: "https://example.com/[text]\n----\nhttps://example.com/?utm_source=y\n----\n",
+		},
+		{
+			name:     "Bookmark export cleans bookmark URLs only",
+			path:     "bookmarks.html",
+			input:    "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<DL><p>\n<DT><A HREF=\"https://example.com/?utm_source=x\" ICON_URI=\"https://example.com/i.ico?utm_source=y\">a</A>\n</DL><p>\n",
+			expected: "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<DL><p>\n<DT><A HREF=\"https://example.com/\" ICON_URI=\"https://example.com/i.ico?utm_source=y\">a</A>\n</DL><p>\n",
+		},
+		{
+			name:     "Text file is cleaned as Markdown",
+			path:     "notes.txt",
+			input:    "[  text  ](https://example.com/?utm_source=x)\n```\nhttps://example.com/?utm_source=y\n```\n",
+			expected: "[text](https://example.com/)\n```\nhttps://example.com/?utm_source=y\n```\n",
+		},
+		{
+			name:     "Text file of unknown extension is skipped",
+			path:     "style.css",
+			input:    "a { background: url(https://example.com/?utm_source=x) }\n",
+			expected: "a { background: url(https://example.com/?utm_source=x) }\n",
+		},
+		{
+			name:     "JSON detected from content",
+			path:     "export",
+			input:    `{"url": "https://example.com/?utm_source=x"}`,
+			expected: `{"url": "https://example.com/"}`,
+		},
+		{
+			name:     "YAML file cleans URL values only",
+			path:     "config.yml",
+			input:    "home: https://example.com/?utm_source=x # comment\nnote: see https://example.com/?utm_source=y\n",
+			expected: "home: https://example.com/ # comment\nnote: see https://example.com/?utm_source=y\n",
+		},
+		{
+			name:     "TOML file",
+			path:     "config.toml",
+			input:    "home = 'https://example.com/?utm_source=x'\n",
+			expected: "home = 'https://example.com/'\n",
+		},
+		{
+			name:     "Feed detected from content",
+			path:     "feed.xml",
+			input:    "<?xml version=\"1.0\"?>\n<rss version=\"2.0\"><channel><link>https://example.com/?utm_source=x</link></channel></rss>\n",
+			expected: "<?xml version=\"1.0\"?>\n<rss version=\"2.0\"><channel><link>https://example.com/</link></channel></rss>\n",
+		},
+		{
+			name:     "Shell script is skipped",
+			path:     "deploy",
+			input:    "#!/bin/sh\ncurl https://example.com/?utm_source=x\n",
+			expected: "#!/bin/sh\ncurl https://example.com/?utm_source=x\n",
+		},
+		{
+			name:     "Binary content is skipped",
+			path:     "notes.md",
+			input:    "https://example.com/?utm_source=x\x00",
+			expected: "https://example.com/?utm_source=x\x00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := pipeline.ApplyFile(tc.path, []byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(result)); diff != "" {
+				t.Errorf("Unexpected content (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
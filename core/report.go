@@ -0,0 +1,31 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// URLChange describes a single URL rewritten by the transform chain.
+type URLChange struct {
+	Original   string   `json:"original"`
+	Cleaned    string   `json:"cleaned"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	Transforms []string `json:"transforms"`
+}
+
+// FileReport lists the URL changes made to one file.
+type FileReport struct {
+	Path    string      `json:"path"`
+	Changes []URLChange `json:"changes"`
+}
+
+// writeReport writes report to w as a single JSON line.
+func writeReport(w io.Writer, report FileReport) error {
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		return fmt.Errorf("failed to write report for %s: %w", report.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProcessFileWithOptionsReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	err := os.WriteFile(path, []byte("https://example.com/?utm_source=x\n"), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var report bytes.Buffer
+	opts := Options{Report: &report}
+	_, err = ProcessFileWithOptions(logr.Discard(), path, opts, NewPipeline([]links.Transform{links.GenericTracking}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got FileReport
+	if err := json.Unmarshal(report.Bytes(), &got); err != nil {
+		t.Fatalf("Report is not valid JSON: %v\n%s", err, report.String())
+	}
+
+	expected := FileReport{
+		Path: path,
+		Changes: []URLChange{
+			{
+				Original:   "https://example.com/?utm_source=x",
+				Cleaned:    "https://example.com/",
+				Line:       1,
+				Column:     1,
+				Transforms: []string{"generic-tracking"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Unexpected report (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gkwa/littlewill/core/links"
+)
+
+// NamedTransform pairs a transform with the name it is reported under.
+type NamedTransform struct {
+	Name string
+	Func func(io.Reader, io.Writer) error
+}
+
+// Funcs returns the transform functions in order, dropping their names.
+func Funcs(transforms []NamedTransform) []func(io.Reader, io.Writer) error {
+	funcs := make([]func(io.Reader, io.Writer) error, 0, len(transforms))
+	for _, transform := range transforms {
+		funcs = append(funcs, transform.Func)
+	}
+	return funcs
+}
+
+// TraceURL runs rawURL through the URL transforms of the pipeline as
+// cleaning a document does, and returns every rewrite along with the result.
+func (p Pipeline) TraceURL(rawURL string) ([]links.TraceStep, string, error) {
+	if p.Engine == nil {
+		return nil, rawURL, nil
+	}
+	result, steps, err := p.Engine.TraceURL(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return steps, result, nil
+}
+
+// DescribeURLChange lists the differences between two URLs in human readable form:
+// removed and added query parameters and rewritten scheme, host, path or fragment.
+func DescribeURLChange(before, after string) []string {
+	if before == after {
+		return nil
+	}
+
+	b, errBefore := url.Parse(before)
+	a, errAfter := url.Parse(after)
+	if errBefore != nil || errAfter != nil {
+		return []string{fmt.Sprintf("rewritten: %s -> %s", before, after)}
+	}
+
+	var changes []string
+	if b.Scheme != a.Scheme {
+		changes = append(changes, fmt.Sprintf("scheme: %s -> %s", b.Scheme, a.Scheme))
+	}
+	if b.Host != a.Host {
+		changes = append(changes, fmt.Sprintf("host: %s -> %s", b.Host, a.Host))
+	}
+	if b.EscapedPath() != a.EscapedPath() {
+		changes = append(changes, fmt.Sprintf("path: %s -> %s", b.EscapedPath(), a.EscapedPath()))
+	}
+
+	bq, aq := b.Query(), a.Query()
+	if removed := missingKeys(bq, aq); len(removed) > 0 {
+		changes = append(changes, "removed params: "+strings.Join(removed, ", "))
+	}
+	if added := missingKeys(aq, bq); len(added) > 0 {
+		changes = append(changes, "added params: "+strings.Join(added, ", "))
+	}
+	if len(changes) == 0 && b.RawQuery != a.RawQuery {
+		changes = append(changes, fmt.Sprintf("query: %s -> %s", b.RawQuery, a.RawQuery))
+	}
+
+	if b.Fragment != a.Fragment {
+		changes = append(changes, fmt.Sprintf("fragment: %q -> %q", b.Fragment, a.Fragment))
+	}
+
+	if len(changes) == 0 {
+		changes = append(changes, "re-encoded")
+	}
+	return changes
+}
+
+// missingKeys returns the sorted keys of from that are absent in to
+func missingKeys(from, to url.Values) []string {
+	var keys []string
+	for key := range from {
+		if !to.Has(key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
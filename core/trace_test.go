@@ -0,0 +1,135 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/gkwa/littlewill/core/links"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTraceURL(t *testing.T) {
+	testCases := []struct {
+		name           string
+		pipeline       Pipeline
+		input          string
+		expectedSteps  []links.TraceStep
+		expectedResult string
+	}{
+		{
+			name:     "Only rewrites are traced",
+			pipeline: NewPipeline([]links.Transform{links.Google, links.Reddit}),
+			input:    "https://www.reddit.com/r/golang?share_id=abc",
+			expectedSteps: []links.TraceStep{
+				{
+					Transform: "reddit",
+					Before:    "https://www.reddit.com/r/golang?share_id=abc",
+					After:     "https://www.reddit.com/r/golang/",
+				},
+			},
+			expectedResult: "https://www.reddit.com/r/golang/",
+		},
+		{
+			name:     "Transforms matching a rewritten host run after it",
+			pipeline: NewPipeline([]links.Transform{links.GenericTracking, links.YouTube}),
+			input:    "https://www.youtube.com/watch?v=abc&utm_source=x&si=y",
+			expectedSteps: []links.TraceStep{
+				{
+					Transform: "generic-tracking",
+					Before:    "https://www.youtube.com/watch?v=abc&utm_source=x&si=y",
+					After:     "https://www.youtube.com/watch?si=y&v=abc",
+				},
+				{
+					Transform: "youtube",
+					Before:    "https://www.youtube.com/watch?si=y&v=abc",
+					After:     "https://youtu.be/abc",
+				},
+			},
+			expectedResult: "https://youtu.be/abc",
+		},
+		{
+			name: "Link text syncing is not a URL step",
+			pipeline: func() Pipeline {
+				p := NewPipeline([]links.Transform{links.GenericTracking})
+				p.Engine.SyncLinkText = true
+				return p
+			}(),
+			input: "https://example.com/a?utm_source=x",
+			expectedSteps: []links.TraceStep{
+				{
+					Transform: "generic-tracking",
+					Before:    "https://example.com/a?utm_source=x",
+					After:     "https://example.com/a",
+				},
+			},
+			expectedResult: "https://example.com/a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps, result, err := tc.pipeline.TraceURL(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedSteps, steps); diff != "" {
+				t.Errorf("Unexpected steps (-want +got):\n%s", diff)
+			}
+			if result != tc.expectedResult {
+				t.Errorf("Result = %q, want %q", result, tc.expectedResult)
+			}
+		})
+	}
+}
+
+func TestDescribeURLChange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		before   string
+		after    string
+		expected []string
+	}{
+		{
+			name:     "Unchanged URL",
+			before:   "https://example.com/a",
+			after:    "https://example.com/a",
+			expected: nil,
+		},
+		{
+			name:     "Removed params are sorted",
+			before:   "https://www.google.com/search?q=go&hl=en&ei=abc",
+			after:    "https://www.google.com/search?q=go",
+			expected: []string{"removed params: ei, hl"},
+		},
+		{
+			name:   "Host and path rewrite",
+			before: "https://www.youtube.com/watch?v=abc",
+			after:  "https://youtu.be/abc",
+			expected: []string{
+				"host: www.youtube.com -> youtu.be",
+				"path: /watch -> /abc",
+				"removed params: v",
+			},
+		},
+		{
+			name:     "Fragment removal",
+			before:   "https://example.com/a#:~:text=hi",
+			after:    "https://example.com/a",
+			expected: []string{`fragment: ":~:text=hi" -> ""`},
+		},
+		{
+			name:     "Parameter reordering",
+			before:   "https://example.com/a?b=2&a=1",
+			after:    "https://example.com/a?a=1&b=2",
+			expected: []string{"query: b=2&a=1 -> a=1&b=2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := DescribeURLChange(tc.before, tc.after)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
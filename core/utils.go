@@ -2,10 +2,15 @@ package core
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 )
 
 func ApplyTransforms(content []byte, transforms ...func(io.Reader, io.Writer) error) ([]byte, error) {
+	if len(transforms) == 0 {
+		return content, nil
+	}
+
 	var processedContent bytes.Buffer
 	currentContent := bytes.NewReader(content)
 
@@ -20,3 +25,23 @@ func ApplyTransforms(content []byte, transforms ...func(io.Reader, io.Writer) er
 
 	return processedContent.Bytes(), nil
 }
+
+// ProcessStream reads all of r, applies the pipeline and writes the result to w.
+func ProcessStream(r io.Reader, w io.Writer, pipeline Pipeline) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	processedContent, _, err := pipeline.Apply(content)
+	if err != nil {
+		return fmt.Errorf("failed to process input: %w", err)
+	}
+
+	_, err = w.Write(processedContent)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
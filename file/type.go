@@ -1,7 +1,11 @@
 package file
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +15,139 @@ type File struct {
 	Path string
 }
 
+// Kind is the kind of content a file holds, as far as cleaning it goes
+type Kind int
+
+const (
+	Unknown Kind = iota
+	Markdown
+	HTML
+	Text
+	JSON
+	YAML
+	TOML
+	Org
+	RST
+	AsciiDoc
+	RSS
+	Atom
+	OPML
+	NetscapeBookmarks
+	ChromeBookmarks
+	FirefoxBookmarks
+	Binary
+)
+
+var kindNames = map[Kind]string{
+	Unknown:           "unknown",
+	Markdown:          "markdown",
+	HTML:              "html",
+	Text:              "text",
+	JSON:              "json",
+	YAML:              "yaml",
+	TOML:              "toml",
+	Org:               "org",
+	RST:               "rst",
+	AsciiDoc:          "asciidoc",
+	RSS:               "rss",
+	Atom:              "atom",
+	OPML:              "opml",
+	NetscapeBookmarks: "netscape-bookmarks",
+	ChromeBookmarks:   "chrome-bookmarks",
+	FirefoxBookmarks:  "firefox-bookmarks",
+	Binary:            "binary",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// sniffLen is how much of a file's content detection looks at, the same
+// amount http.DetectContentType considers
+const sniffLen = 512
+
+// extensionKinds maps file extensions to the kind of content they hold
+var extensionKinds = map[string]Kind{
+	".markdown": Markdown,
+	".md":       Markdown,
+	".mdown":    Markdown,
+	".mdx":      Markdown,
+	".mkd":      Markdown,
+	".htm":      HTML,
+	".html":     HTML,
+	".xhtml":    HTML,
+	".text":     Text,
+	".txt":      Text,
+	".json":     JSON,
+	".yaml":     YAML,
+	".yml":      YAML,
+	".toml":     TOML,
+	".org":      Org,
+	".rest":     RST,
+	".rst":      RST,
+	".adoc":     AsciiDoc,
+	".asciidoc": AsciiDoc,
+	".rss":      RSS,
+	".atom":     Atom,
+	".opml":     OPML,
+
+	".7z":     Binary,
+	".avif":   Binary,
+	".bin":    Binary,
+	".bz2":    Binary,
+	".class":  Binary,
+	".dll":    Binary,
+	".dmg":    Binary,
+	".exe":    Binary,
+	".gif":    Binary,
+	".gz":     Binary,
+	".ico":    Binary,
+	".jar":    Binary,
+	".jpeg":   Binary,
+	".jpg":    Binary,
+	".mov":    Binary,
+	".mp3":    Binary,
+	".mp4":    Binary,
+	".o":      Binary,
+	".pdf":    Binary,
+	".png":    Binary,
+	".so":     Binary,
+	".sqlite": Binary,
+	".tar":    Binary,
+	".webp":   Binary,
+	".xz":     Binary,
+	".zip":    Binary,
+}
+
+// descriptions are the human readable names FileType reports
+var descriptions = map[string]string{
+	".txt":  "Text File",
+	".go":   "Go Source File",
+	".jpg":  "JPEG Image",
+	".jpeg": "JPEG Image",
+	".png":  "PNG Image",
+	".pdf":  "PDF Document",
+}
+
+var kindDescriptions = map[Kind]string{
+	Markdown:          "Markdown File",
+	HTML:              "HTML File",
+	Text:              "Text File",
+	JSON:              "JSON File",
+	YAML:              "YAML File",
+	TOML:              "TOML File",
+	Org:               "Org File",
+	RST:               "reStructuredText File",
+	AsciiDoc:          "AsciiDoc File",
+	RSS:               "RSS Feed",
+	Atom:              "Atom Feed",
+	OPML:              "OPML Outline",
+	NetscapeBookmarks: "Bookmarks File",
+	ChromeBookmarks:   "Chrome Bookmarks File",
+	FirefoxBookmarks:  "Firefox Bookmarks Backup",
+	Binary:            "Binary File",
+}
+
 func (f File) IsSymlink() (bool, error) {
 	fileInfo, err := os.Lstat(f.Path)
 	if err != nil {
@@ -41,19 +178,155 @@ func (f File) FileType() string {
 		return "Directory"
 	}
 
+	// the description goes by the name alone, as content detection is left
+	// to Detect
 	ext := strings.ToLower(filepath.Ext(f.Path))
-	switch ext {
-	case ".txt":
-		return "Text File"
-	case ".go":
-		return "Go Source File"
-	case ".jpg", ".jpeg":
-		return "JPEG Image"
-	case ".png":
-		return "PNG Image"
-	case ".pdf":
-		return "PDF Document"
-	default:
-		return "Unknown File Type"
+	if description, ok := descriptions[ext]; ok {
+		return description
+	}
+	if description, ok := kindDescriptions[extensionKinds[ext]]; ok {
+		return description
+	}
+	return "Unknown File Type"
+}
+
+// Detect returns the kind of the file's content, judged from its extension
+// and its first bytes.
+func (f File) Detect() (Kind, error) {
+	fh, err := os.Open(f.Path)
+	if err != nil {
+		return Unknown, err
+	}
+	defer fh.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(fh, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Unknown, err
+	}
+	return DetectContent(f.Path, head[:n]), nil
+}
+
+// DetectContent returns the kind of content from a file name and the
+// content's first bytes. Content that is not text is Binary whatever its
+// extension claims, and bookmark exports and feeds are recognized by their
+// content whatever their name. Other text is classified by its extension,
+// and is Unknown if the extension is not one of the known ones. Files without
+// an extension are judged from their first bytes, where scripts starting with
+// a shebang and anything else that is not recognizably HTML, JSON, YAML or
+// plain text are Unknown.
+func DetectContent(name string, content []byte) Kind {
+	head := content
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+
+	kind, known := extensionKinds[ext]
+	if kind == Binary || !isText(head) {
+		return Binary
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bookmarks := bookmarkKind(trimmed); bookmarks != Unknown {
+		return bookmarks
+	}
+	if feed := feedKind(trimmed); feed != Unknown {
+		return feed
+	}
+	if known {
+		return kind
+	}
+	if ext != "" || bytes.HasPrefix(head, []byte("#!")) {
+		return Unknown
+	}
+
+	switch {
+	case strings.HasPrefix(http.DetectContentType(head), "text/html"):
+		return HTML
+	case looksLikeJSON(trimmed, len(content) <= sniffLen):
+		return JSON
+	case bytes.HasPrefix(trimmed, []byte("%YAML")):
+		return YAML
+	case len(trimmed) > 0:
+		return Text
+	}
+	return Unknown
+}
+
+// bookmarkKind returns the kind of bookmark export trimmed starts, or Unknown
+func bookmarkKind(trimmed []byte) Kind {
+	switch {
+	case bytes.HasPrefix(bytes.ToUpper(trimmed), []byte("<!DOCTYPE NETSCAPE-BOOKMARK-FILE-1>")):
+		return NetscapeBookmarks
+	case len(trimmed) == 0 || trimmed[0] != '{':
+		return Unknown
+	case bytes.Contains(trimmed, []byte(`"roots"`)) && bytes.Contains(trimmed, []byte(`"bookmark_bar"`)):
+		return ChromeBookmarks
+	case bytes.Contains(trimmed, []byte(`"root":"placesRoot"`)) || bytes.Contains(trimmed, []byte(`"type":"text/x-moz-place-container"`)):
+		return FirefoxBookmarks
+	}
+	return Unknown
+}
+
+// feedRoots are the root elements of the XML documents feedKind recognizes
+var feedRoots = []struct {
+	name string
+	kind Kind
+}{
+	{"<rss", RSS},
+	{"<rdf:rdf", RSS},
+	{"<feed", Atom},
+	{"<opml", OPML},
+}
+
+// feedKind returns the kind of feed trimmed starts, judged from its root
+// element, or Unknown
+func feedKind(trimmed []byte) Kind {
+	// skip the XML declaration, comments and any doctype
+	for bytes.HasPrefix(trimmed, []byte("<?")) || bytes.HasPrefix(trimmed, []byte("<!")) {
+		end := []byte(">")
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("<?")):
+			end = []byte("?>")
+		case bytes.HasPrefix(trimmed, []byte("<!--")):
+			end = []byte("-->")
+		}
+		i := bytes.Index(trimmed, end)
+		if i < 0 {
+			return Unknown
+		}
+		trimmed = bytes.TrimLeft(trimmed[i+len(end):], " \t\r\n")
+	}
+
+	lower := bytes.ToLower(trimmed)
+	for _, root := range feedRoots {
+		rest, ok := bytes.CutPrefix(lower, []byte(root.name))
+		if ok && len(rest) > 0 && strings.IndexByte(" \t\r\n>", rest[0]) >= 0 {
+			return root.kind
+		}
+	}
+	return Unknown
+}
+
+// isText reports whether head looks like text rather than binary data
+func isText(head []byte) bool {
+	// NUL bytes include UTF-16 text, which is not cleaned either
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	mime := http.DetectContentType(head)
+	return strings.HasPrefix(mime, "text/") || strings.HasPrefix(mime, "application/json")
+}
+
+// looksLikeJSON reports whether trimmed starts a JSON object or array. When
+// complete holds the whole content, it must also be valid JSON.
+func looksLikeJSON(trimmed []byte, complete bool) bool {
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	if complete {
+		return json.Valid(trimmed)
 	}
+	rest := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+	return len(rest) > 0 && strings.IndexByte(`"{[]}-0123456789tfn`, rest[0]) >= 0
 }
@@ -102,3 +102,51 @@ func TestFileType(t *testing.T) {
 		})
 	}
 }
+
+func TestDetectContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name     string
+		path     string
+		content  []byte
+		expected Kind
+	}{
+		{name: "Markdown by extension", path: "notes.md", content: []byte("# Notes\n"), expected: Markdown},
+		{name: "Extension is case insensitive", path: "README.MD", content: []byte("# Notes\n"), expected: Markdown},
+		{name: "HTML by extension", path: "page.htm", content: []byte("<p>hi</p>"), expected: HTML},
+		{name: "Text by extension", path: "links.txt", content: []byte("https://example.com\n"), expected: Text},
+		{name: "JSON by extension", path: "data.json", content: []byte(`{"a": 1}`), expected: JSON},
+		{name: "YAML by extension", path: "config.yml", content: []byte("a: 1\n"), expected: YAML},
+		{name: "RSS by content", path: "feed.xml", content: []byte("<?xml version=\"1.0\"?>\n<!-- feed -->\n<rss version=\"2.0\">"), expected: RSS},
+		{name: "Atom by content", path: "index.xml", content: []byte("<feed xmlns=\"http://www.w3.org/2005/Atom\">"), expected: Atom},
+		{name: "OPML by extension", path: "subscriptions.opml", content: []byte("<?xml version=\"1.0\"?>\n<opml version=\"2.0\">"), expected: OPML},
+		{name: "Other XML is unknown", path: "pom.xml", content: []byte("<?xml version=\"1.0\"?>\n<project>"), expected: Unknown},
+		{name: "TOML by extension", path: "hugo.toml", content: []byte("a = 1\n"), expected: TOML},
+		{name: "Org by extension", path: "notes.org", content: []byte("* Notes\n"), expected: Org},
+		{name: "reStructuredText by extension", path: "index.rst", content: []byte("Title\n=====\n"), expected: RST},
+		{name: "AsciiDoc by extension", path: "guide.adoc", content: []byte("= Guide\n"), expected: AsciiDoc},
+		{name: "Netscape bookmarks whatever the extension", path: "bookmarks.html", content: []byte("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<DL><p>\n"), expected: NetscapeBookmarks},
+		{name: "Chrome bookmarks", path: "Bookmarks", content: []byte("{\n   \"checksum\": \"\",\n   \"roots\": {\n      \"bookmark_bar\": {"), expected: ChromeBookmarks},
+		{name: "Firefox bookmarks backup", path: "bookmarks-2024-05-01.json", content: []byte(`{"guid":"root________","title":"","type":"text/x-moz-place-container","root":"placesRoot"}`), expected: FirefoxBookmarks},
+		{name: "Binary by extension", path: "photo.jpg", content: []byte("fake jpg"), expected: Binary},
+		{name: "Binary content with a text extension", path: "notes.md", content: png, expected: Binary},
+		{name: "NUL bytes are binary", path: "notes", content: []byte("a\x00b"), expected: Binary},
+		{name: "HTML by content", path: "saved", content: []byte("<!DOCTYPE html><html></html>"), expected: HTML},
+		{name: "JSON by content", path: "data", content: []byte(" [1, 2]\n"), expected: JSON},
+		{name: "Invalid JSON is text", path: "data", content: []byte("{not json"), expected: Text},
+		{name: "YAML by directive", path: "config", content: []byte("%YAML 1.2\n---\na: 1\n"), expected: YAML},
+		{name: "Plain text by content", path: "LINKS", content: []byte("see https://example.com\n"), expected: Text},
+		{name: "Shebang script", path: "deploy", content: []byte("#!/bin/sh\ncurl https://example.com\n"), expected: Unknown},
+		{name: "Source code", path: "main.go", content: []byte("package main\n"), expected: Unknown},
+		{name: "Empty file without extension", path: "empty", content: nil, expected: Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContent(tt.path, tt.content); got != tt.expected {
+				t.Errorf("DetectContent(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+)
+
+const contextLines = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	a, b int // line indexes into the old and new content
+}
+
+type hunk struct {
+	ops []op
+}
+
+// Unified writes a unified diff between a and b to w. Nothing is written when
+// the inputs are equal. When colorize is set, headers, removals and additions
+// are colored the way git colors them.
+func Unified(w io.Writer, fromName, toName string, a, b []byte, colorize bool) error {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+
+	header := newColor(colorize, color.Bold)
+	hunkHeader := newColor(colorize, color.FgCyan)
+	removed := newColor(colorize, color.FgRed)
+	added := newColor(colorize, color.FgGreen)
+
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	ops := editScript(aLines, bLines)
+
+	var buf bytes.Buffer
+	header.Fprintf(&buf, "--- %s\n", fromName)
+	header.Fprintf(&buf, "+++ %s\n", toName)
+
+	for _, h := range hunks(ops) {
+		aStart, aCount, bStart, bCount := h.ranges()
+		hunkHeader.Fprintf(&buf, "@@ -%s +%s @@\n", formatRange(aStart, aCount), formatRange(bStart, bCount))
+
+		for _, o := range h.ops {
+			switch o.kind {
+			case opEqual:
+				writeLine(&buf, nil, " ", aLines[o.a])
+			case opDelete:
+				writeLine(&buf, removed, "-", aLines[o.a])
+			case opInsert:
+				writeLine(&buf, added, "+", bLines[o.b])
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func newColor(colorize bool, attrs ...color.Attribute) *color.Color {
+	c := color.New(attrs...)
+	if colorize {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c
+}
+
+func writeLine(buf *bytes.Buffer, c *color.Color, prefix, line string) {
+	text := prefix + trimNewline(line)
+	if c != nil {
+		c.Fprint(buf, text)
+	} else {
+		buf.WriteString(text)
+	}
+	buf.WriteString("\n")
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		buf.WriteString("\\ No newline at end of file\n")
+	}
+}
+
+func trimNewline(line string) string {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		return line[:len(line)-1]
+	}
+	return line
+}
+
+// splitLines splits content into lines that keep their trailing newline so
+// a missing newline at end of file shows up in the diff.
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+		lines = append(lines, string(content[:i+1]))
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// editScript computes the shortest edit script from a to b using the linear
+// space variant of Myers' O(ND) algorithm, which splits the inputs at the
+// middle snake of an optimal path and recurses on both halves. Lines are
+// interned first so that comparisons are integer comparisons, and lines
+// found on one side only are marked as edits up front, which keeps the script
+// shortest while sparing the algorithm the cost of rewritten files.
+func editScript(a, b []string) []op {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	aIDs, bIDs := intern(a), intern(b)
+
+	inA := make([]bool, len(ids))
+	for _, id := range aIDs {
+		inA[id] = true
+	}
+	inB := make([]bool, len(ids))
+	for _, id := range bIDs {
+		inB[id] = true
+	}
+	deleted := make([]bool, len(a))
+	inserted := make([]bool, len(b))
+	// common returns the lines that may match, marking the others as edits
+	common := func(lineIDs []int, other, edited []bool) (kept, index []int) {
+		for i, id := range lineIDs {
+			if other[id] {
+				kept = append(kept, id)
+				index = append(index, i)
+			} else {
+				edited[i] = true
+			}
+		}
+		return kept, index
+	}
+	aKept, aIndex := common(aIDs, inB, deleted)
+	bKept, bIndex := common(bIDs, inA, inserted)
+
+	d := &differ{
+		a:        aKept,
+		b:        bKept,
+		deleted:  make([]bool, len(aKept)),
+		inserted: make([]bool, len(bKept)),
+	}
+	d.compare(0, len(aKept), 0, len(bKept))
+	for i, edited := range d.deleted {
+		deleted[aIndex[i]] = edited
+	}
+	for i, edited := range d.inserted {
+		inserted[bIndex[i]] = edited
+	}
+
+	ops := make([]op, 0, max(len(a), len(b)))
+	x, y := 0, 0
+	for x < len(a) || y < len(b) {
+		switch {
+		case x < len(a) && deleted[x]:
+			ops = append(ops, op{kind: opDelete, a: x, b: y})
+			x++
+		case y < len(b) && inserted[y]:
+			ops = append(ops, op{kind: opInsert, a: x, b: y})
+			y++
+		default:
+			ops = append(ops, op{kind: opEqual, a: x, b: y})
+			x++
+			y++
+		}
+	}
+	return ops
+}
+
+// differ marks the lines of a that are deleted and the lines of b that are
+// inserted
+type differ struct {
+	a, b              []int
+	deleted, inserted []bool
+	v1, v2            []int // forward and reverse furthest reaching paths, reused across calls
+}
+
+// compare marks the edits between a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.inserted[y] = true
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.deleted[x] = true
+		}
+	default:
+		x, y, ok := d.middleSnake(aLo, aHi, bLo, bHi)
+		if !ok {
+			for x := aLo; x < aHi; x++ {
+				d.deleted[x] = true
+			}
+			for y := bLo; y < bHi; y++ {
+				d.inserted[y] = true
+			}
+			return
+		}
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+}
+
+// middleSnake runs Myers' algorithm from both ends of a[aLo:aHi] and
+// b[bLo:bHi] until the paths overlap, and returns where they meet. It
+// returns false when the inputs have no line in common.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	length := 2*maxD + 2
+	if cap(d.v1) < length {
+		d.v1 = make([]int, length)
+		d.v2 = make([]int, length)
+	}
+	v1, v2 := d.v1[:length], d.v2[:length]
+	for i := range v1 {
+		v1[i] = -1
+		v2[i] = -1
+	}
+	v1[offset+1] = 0
+	v2[offset+1] = 0
+
+	delta := n - m
+	// with an odd delta the forward path meets the reverse one, otherwise
+	// the reverse path meets the forward one
+	front := delta%2 != 0
+	// the diagonals that ran off the edges and need no more work
+	k1Start, k1End, k2Start, k2End := 0, 0, 0, 0
+
+	for e := 0; e < maxD; e++ {
+		for k1 := -e + k1Start; k1 <= e-k1End; k1 += 2 {
+			i := offset + k1
+			var x1 int
+			if k1 == -e || (k1 != e && v1[i-1] < v1[i+1]) {
+				x1 = v1[i+1]
+			} else {
+				x1 = v1[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			v1[i] = x1
+			switch {
+			case x1 > n:
+				k1End += 2
+			case y1 > m:
+				k1Start += 2
+			case front:
+				j := offset + delta - k1
+				if j >= 0 && j < length && v2[j] != -1 && x1 >= n-v2[j] {
+					return aLo + x1, bLo + y1, true
+				}
+			}
+		}
+
+		for k2 := -e + k2Start; k2 <= e-k2End; k2 += 2 {
+			j := offset + k2
+			var x2 int
+			if k2 == -e || (k2 != e && v2[j-1] < v2[j+1]) {
+				x2 = v2[j+1]
+			} else {
+				x2 = v2[j-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			v2[j] = x2
+			switch {
+			case x2 > n:
+				k2End += 2
+			case y2 > m:
+				k2Start += 2
+			case !front:
+				i := offset + delta - k2
+				if i >= 0 && i < length && v1[i] != -1 {
+					x1 := v1[i]
+					y1 := offset + x1 - i
+					if x1 >= n-x2 {
+						return aLo + x1, bLo + y1, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// hunks groups changes with up to contextLines of surrounding context,
+// merging changes whose context would overlap.
+func hunks(ops []op) []hunk {
+	var result []hunk
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		start := max(i-contextLines, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				end = min(end+contextLines, run)
+				break
+			}
+			end = run
+		}
+
+		result = append(result, hunk{ops: ops[start:end]})
+		i = end
+	}
+	return result
+}
+
+func (h hunk) ranges() (aStart, aCount, bStart, bCount int) {
+	first := h.ops[0]
+	aStart, bStart = first.a+1, first.b+1
+	for _, o := range h.ops {
+		switch o.kind {
+		case opEqual:
+			aCount++
+			bCount++
+		case opDelete:
+			aCount++
+		case opInsert:
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+	return aStart, aCount, bStart, bCount
+}
+
+func formatRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,205 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnified(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "Equal content produces no output",
+			a:        "one\ntwo\n",
+			b:        "one\ntwo\n",
+			expected: "",
+		},
+		{
+			name: "Single changed line",
+			a:    "one\nhttps://example.com/?utm_source=x\nthree\n",
+			b:    "one\nhttps://example.com/\nthree\n",
+			expected: `--- a.md
++++ b.md
+@@ -1,3 +1,3 @@
+ one
+-https://example.com/?utm_source=x
++https://example.com/
+ three
+`,
+		},
+		{
+			name: "Distant changes produce separate hunks",
+			a:    "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			b:    "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			expected: `--- a.md
++++ b.md
+@@ -1,4 +1,4 @@
+-a
++A
+ 1
+ 2
+ 3
+@@ -7,4 +7,4 @@
+ 6
+ 7
+ 8
+-b
++B
+`,
+		},
+		{
+			name: "Missing newline at end of file is reported",
+			a:    "one\ntwo",
+			b:    "one\nTWO",
+			expected: `--- a.md
++++ b.md
+@@ -1,2 +1,2 @@
+ one
+-two
+\ No newline at end of file
++TWO
+\ No newline at end of file
+`,
+		},
+		{
+			name: "Insertion into empty content",
+			a:    "",
+			b:    "new\n",
+			expected: `--- a.md
++++ b.md
+@@ -0,0 +1 @@
++new
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := Unified(&out, "a.md", "b.md", []byte(tc.a), []byte(tc.b), false)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, out.String()); diff != "" {
+				t.Errorf("Unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnifiedColor(t *testing.T) {
+	var out bytes.Buffer
+	err := Unified(&out, "a.md", "b.md", []byte("old\n"), []byte("new\n"), true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("\x1b[31m-old")) {
+		t.Errorf("Expected removed line to be red, got %q", out.String())
+	}
+	if !bytes.Contains(out.Bytes(), []byte("\x1b[32m+new")) {
+		t.Errorf("Expected added line to be green, got %q", out.String())
+	}
+}
+
+func TestEditScriptIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() []string {
+		lines := make([]string, rng.IntN(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(3)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(), randomLines()
+		ops := editScript(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, o := range ops {
+			switch o.kind {
+			case opEqual:
+				gotA = append(gotA, a[o.a])
+				gotB = append(gotB, b[o.b])
+			case opDelete:
+				gotA = append(gotA, a[o.a])
+				edits++
+			case opInsert:
+				gotB = append(gotB, b[o.b])
+				edits++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("editScript(%q, %q) does not reproduce its inputs", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("editScript(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+func TestUnifiedLargeInput(t *testing.T) {
+	const lines = 20000
+	var a, everyLine, someLines strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&a, "https://example.com/%d?utm_source=x\n", i)
+		fmt.Fprintf(&everyLine, "https://example.com/%d\n", i)
+		if i%100 == 0 {
+			fmt.Fprintf(&someLines, "https://example.com/%d\n", i)
+		} else {
+			fmt.Fprintf(&someLines, "https://example.com/%d?utm_source=x\n", i)
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		b       string
+		changed int
+	}{
+		{name: "Every line changed", b: everyLine.String(), changed: lines},
+		{name: "Some lines changed", b: someLines.String(), changed: lines / 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Unified(&out, "a.md", "b.md", []byte(a.String()), []byte(tc.b), false); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			removed := strings.Count(out.String(), "\n-https://")
+			added := strings.Count(out.String(), "\n+https://")
+			if removed != tc.changed || added != tc.changed {
+				t.Errorf("Got %d removed and %d added lines, want %d of each", removed, added, tc.changed)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package watcher
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,7 +40,7 @@ func cleanupBrokenSymlinks(dir string) error {
 		}
 
 		if isBrokenSymlink(path) {
-			fmt.Printf("Removing broken symlink: %s\n", path)
+			fmt.Fprintf(os.Stderr, "Removing broken symlink: %s\n", path)
 			return os.Remove(path)
 		}
 		return nil
@@ -53,7 +52,7 @@ func addWatchWithCleanup(w *fsnotify.Watcher, dirPath string) error {
 	if err != nil && strings.Contains(err.Error(), "no such file or directory") {
 		// Extract the problematic file path from the error message
 		if strings.Contains(err.Error(), ".#") {
-			fmt.Printf("Broken symlink detected, cleaning up and retrying...\n")
+			fmt.Fprintf(os.Stderr, "Broken symlink detected, cleaning up and retrying...\n")
 			cleanupBrokenSymlinks(dirPath)
 
 			// Retry after cleanup
@@ -69,15 +68,16 @@ func RunWatcher(
 	dirToWatch string,
 	patterns []string,
 	filterType string,
-	linkTransforms []func(io.Reader, io.Writer) error,
+	opts core.Options,
+	pipeline core.Pipeline,
 ) {
 	logger := logr.FromContextOrDiscard(ctx)
 
 	handler := func(event fsnotify.Event, path string) {
 		time.Sleep(time.Second)
-		fmt.Printf("Event: %s, File: %s\n", event.Op, path)
+		logger.Info("Processing file", "event", event.Op.String(), "file", path)
 
-		err := core.ProcessFile(logger, path, linkTransforms...)
+		_, err := core.ProcessFileWithOptions(logger, path, opts, pipeline)
 		if err != nil {
 			logger.Error(err, "Failed to process file", "path", path)
 			// Don't exit on file processing errors, just continue watching